@@ -0,0 +1,510 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/keyring"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	exitSuccess        = 0
+	exitFailure        = 1
+	exitUsage          = 2
+	exitPartialFailure = 3
+)
+
+type command struct {
+	name        string
+	description string
+}
+
+var commands = []command{
+	{"download", "Download URLs, text files with URLs, track lists or - for stdin (default command)"},
+	{"search", "Search the catalog"},
+	{"info", "Print the metadata of URLs as JSON"},
+	{"verify", "Check the FLAC and M4A files in directories for corruption"},
+	{"scan", "Match the files in directories to Beatport tracks and report the untagged, mismatched and missing ones"},
+	{"upgrade", "Replace the files in directories that are below the configured quality with a better version"},
+	{"import", "Match the tracks of CSV, M3U, Rekordbox XML, Traktor NML or text track lists and download them"},
+	{"login", "Log in to every store with an account (or -store) and cache the access tokens, -code/-token log in without a password"},
+	{"logout", "Revoke the access tokens and delete the token caches"},
+	{"whoami", "Print the logged in accounts"},
+	{"config", "Print the config file path (path) or the effective config (show [-sources]), set a config value (set) or move the password of a store to the OS keyring (keyring [-store])"},
+	{"watch", "Manage and run label, artist, playlist and chart subscriptions"},
+	{"help", "Show this help"},
+}
+
+var (
+	ErrNoUrls = errors.New("no urls provided")
+)
+
+type configOverride struct {
+	key   string
+	value string
+}
+
+type cliFlags struct {
+	quit           bool
+	nonInteractive bool
+	profile        string
+	overrides      []configOverride
+	filters        filterFlags
+}
+
+// register adds the global flags to fs. The same flags are accepted before
+// the command and after the download command.
+func (f *cliFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.quit, "q", f.quit, "Quit the main loop after finishing")
+	fs.BoolVar(&f.nonInteractive, "non-interactive", f.nonInteractive, "Never prompt for input or wait for Enter (implies -q)")
+	if f.profile == "" {
+		f.profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+	fs.BoolFunc("v", "Verbose output, same as -log-level debug", func(string) error {
+		f.overrides = append(f.overrides, configOverride{"log_level", "debug"})
+		return nil
+	})
+	fs.StringVar(&f.profile, "profile", f.profile, "Config profile to use (default from BEATPORTDL_PROFILE)")
+	fs.Func("set", "Override any config value (key=value, e.g. filters.bpm_min=120)", func(s string) error {
+		key, value, found := strings.Cut(s, "=")
+		if !found {
+			return fmt.Errorf("expected key=value: %s", s)
+		}
+		f.overrides = append(f.overrides, configOverride{key, value})
+		return nil
+	})
+	for _, key := range config.ScalarKeys() {
+		name := strings.ReplaceAll(key, "_", "-")
+		usage := fmt.Sprintf("Override the %s config value", key)
+		set := func(value string) error {
+			f.overrides = append(f.overrides, configOverride{key, value})
+			return nil
+		}
+		if config.IsBoolKey(key) {
+			fs.BoolFunc(name, usage, set)
+		} else {
+			fs.Func(name, usage, set)
+		}
+	}
+	f.filters.register(fs)
+}
+
+// applyConfigSources layers the environment variables, the command line flags
+// and the secret sources on top of the loaded config file.
+func applyConfigSources(cfg *config.AppConfig, flags *cliFlags) error {
+	if err := cfg.ApplyEnv(); err != nil {
+		return err
+	}
+	if err := flags.apply(cfg); err != nil {
+		return err
+	}
+	return cfg.ResolveCredentials()
+}
+
+// apply overrides the config values with the ones provided on the command line.
+func (f *cliFlags) apply(cfg *config.AppConfig) error {
+	for _, override := range f.overrides {
+		if err := cfg.Set(override.key, override.value); err != nil {
+			return err
+		}
+	}
+	return f.filters.apply(&cfg.Filters)
+}
+
+func isCommand(name string) bool {
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: beatportdl [flags] [command] [arguments]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(out, "\nExit codes:")
+	fmt.Fprintf(out, "  %d  success\n", exitSuccess)
+	fmt.Fprintf(out, "  %d  failure (fatal error or nothing could be downloaded)\n", exitFailure)
+	fmt.Fprintf(out, "  %d  invalid usage\n", exitUsage)
+	fmt.Fprintf(out, "  %d  partial failure (some items failed, or the run was stopped by a signal)\n", exitPartialFailure)
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return true
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func (app *application) exitCode() int {
+	switch {
+	case app.failed.Load() == 0 && !app.interrupted.Load():
+		return exitSuccess
+	case app.succeeded.Load() == 0:
+		return exitFailure
+	default:
+		return exitPartialFailure
+	}
+}
+
+func (app *application) login(args []string) {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	storeFlag := fs.String("store", "", "Only log in to this store (beatport, beatsource)")
+	codeFlag := fs.String("code", "", "Log in with an authorization code or the URL it was shown on (- to open the login page and paste it)")
+	codeFileFlag := fs.String("code-file", "", "Log in with an authorization code read from a file")
+	tokenFlag := fs.String("token", "", "Log in with a refresh token (- to paste it)")
+	tokenFileFlag := fs.String("token-file", "", "Log in with a refresh token read from a file")
+	fs.Parse(args)
+
+	if *codeFlag != "" || *codeFileFlag != "" || *tokenFlag != "" || *tokenFileFlag != "" {
+		store := beatport.StoreBeatport
+		if *storeFlag != "" {
+			store = beatport.Store(*storeFlag)
+		}
+		inst, err := app.storeInstance(store)
+		if err != nil {
+			app.FatalError("login", err)
+		}
+		if err := app.loginWithToken(inst, *codeFlag, *codeFileFlag, *tokenFlag, *tokenFileFlag); err != nil {
+			app.FatalError("login", err)
+		}
+		app.LogInfo(fmt.Sprintf("Logged in to %s", store))
+		return
+	}
+
+	stores := []*beatport.Beatport{app.bp, app.bs}
+	if *storeFlag != "" {
+		inst, err := app.storeInstance(beatport.Store(*storeFlag))
+		if err != nil {
+			app.FatalError("login", err)
+		}
+		stores = []*beatport.Beatport{inst}
+	}
+
+	for _, inst := range stores {
+		if !inst.HasCredentials() && *storeFlag == "" {
+			continue
+		}
+		if err := inst.Login(); err != nil {
+			app.FatalError("login", err)
+		}
+		username, _ := app.config.Credentials(string(inst.Store()))
+		app.LogInfo(fmt.Sprintf("Logged in to %s as %s", inst.Store(), username))
+	}
+}
+
+// loginWithToken logs in with an authorization code or a refresh token that
+// is passed directly, read from a file or pasted.
+func (app *application) loginWithToken(inst *beatport.Beatport, code, codeFile, token, tokenFile string) error {
+	var err error
+	switch {
+	case codeFile != "":
+		if code, err = readTokenFile(codeFile); err != nil {
+			return err
+		}
+	case tokenFile != "":
+		if token, err = readTokenFile(tokenFile); err != nil {
+			return err
+		}
+	case code == "-":
+		if interactive {
+			fmt.Println("Log in on this page and paste the code or the URL you are redirected to:")
+			fmt.Println(inst.AuthorizeUrl())
+		}
+		code = app.readTokenLine("Code: ")
+	case token == "-":
+		token = app.readTokenLine("Refresh token: ")
+	}
+
+	if token != "" {
+		return inst.Auth().LoginWithRefreshToken(inst, token)
+	}
+	if code, err = beatport.ParseAuthorizationCode(code); err != nil {
+		return err
+	}
+	return inst.Auth().LoginWithCode(inst, code)
+}
+
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (app *application) readTokenLine(prompt string) string {
+	if interactive {
+		fmt.Print(prompt)
+	}
+	return strings.TrimSpace(app.promptLine())
+}
+
+func (app *application) logout(args []string) {
+	fs := flag.NewFlagSet("logout", flag.ExitOnError)
+	storeFlag := fs.String("store", "", "Only log out of this store (beatport, beatsource)")
+	fs.Parse(args)
+
+	stores := []*beatport.Beatport{app.bp, app.bs}
+	if *storeFlag != "" {
+		inst, err := app.storeInstance(beatport.Store(*storeFlag))
+		if err != nil {
+			app.FatalError("logout", err)
+		}
+		stores = []*beatport.Beatport{inst}
+	}
+
+	for _, inst := range stores {
+		if err := inst.Auth().Logout(inst); err != nil {
+			app.FatalError("logout", err)
+		}
+		app.LogInfo(fmt.Sprintf("Logged out of %s", inst.Store()))
+	}
+}
+
+func (app *application) whoami(args []string) {
+	fs := flag.NewFlagSet("whoami", flag.ExitOnError)
+	storeFlag := fs.String("store", string(beatport.StoreBeatport), "Store of the account (beatport, beatsource)")
+	fs.Parse(args)
+
+	inst, err := app.storeInstance(beatport.Store(*storeFlag))
+	if err != nil {
+		app.FatalError("whoami", err)
+	}
+	account, err := inst.GetAccount()
+	if err != nil {
+		app.FatalError("whoami", err)
+	}
+
+	fmt.Printf("%s (%s)\n", account.Username, account.Email)
+	if name := strings.TrimSpace(account.FirstName + " " + account.LastName); name != "" {
+		fmt.Println("Name:", name)
+	}
+	fmt.Println("Store:", inst.Store())
+	if expiry, ok := inst.Auth().TokenExpiry(); ok {
+		fmt.Println("Token expires:", expiry.Format(time.DateTime))
+	}
+}
+
+func (app *application) infoCommand(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: beatportdl info <url>...")
+		os.Exit(exitUsage)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", " ")
+	for _, url := range fs.Args() {
+		link, err := app.bp.ParseUrl(url)
+		if err != nil {
+			app.errorLogWrapper(url, "parse url", err)
+			continue
+		}
+		inst, err := app.storeInstance(link.Store)
+		if err != nil {
+			app.errorLogWrapper(url, "handle URL", err)
+			continue
+		}
+		entity, err := fetchEntity(inst, link)
+		if err != nil {
+			app.errorLogWrapper(url, "fetch info", err)
+			continue
+		}
+		if err := encoder.Encode(entity); err != nil {
+			app.errorLogWrapper(url, "encode info", err)
+			continue
+		}
+		app.succeeded.Add(1)
+	}
+}
+
+func fetchEntity(inst *beatport.Beatport, link *beatport.Link) (any, error) {
+	switch link.Type {
+	case beatport.TrackLink:
+		return inst.GetTrack(link.ID)
+	case beatport.ReleaseLink:
+		return inst.GetRelease(link.ID)
+	case beatport.PlaylistLink:
+		return inst.GetPlaylist(link.ID)
+	case beatport.ChartLink:
+		return inst.GetChart(link.ID)
+	case beatport.LabelLink:
+		return inst.GetLabel(link.ID)
+	case beatport.ArtistLink:
+		return inst.GetArtist(link.ID)
+	case beatport.GenreTopLink, beatport.GenreHypeLink, beatport.GenreReleasesLink:
+		return inst.GetGenreFeed(link)
+	default:
+		return nil, ErrUnsupportedLinkType
+	}
+}
+
+func configCommand(args []string, flags *cliFlags) {
+	if len(args) == 0 {
+		fmt.Println("Usage: beatportdl config <path|show|set|keyring> [arguments]")
+		os.Exit(exitUsage)
+	}
+
+	configFilePath, exists, err := FindConfigFile()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(exitFailure)
+	}
+
+	switch args[0] {
+	case "path":
+		fmt.Println(configFilePath)
+		return
+	case "show", "set", "keyring":
+	default:
+		fmt.Printf("Unknown config command: %s\n", args[0])
+		os.Exit(exitUsage)
+	}
+
+	if !exists {
+		fmt.Println("Config file not found:", configFilePath)
+		os.Exit(exitFailure)
+	}
+	cfg, err := config.Load(configFilePath)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(exitFailure)
+	}
+	fileConfig := cfg
+	if args[0] != "set" {
+		if cfg, err = cfg.WithProfile(flags.profile); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitUsage)
+		}
+	}
+
+	switch args[0] {
+	case "show":
+		fs := flag.NewFlagSet("config show", flag.ExitOnError)
+		sourcesFlag := fs.Bool("sources", false, "Print where each value comes from")
+		fs.Parse(args[1:])
+
+		if err := applyConfigSources(cfg, flags); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitUsage)
+		}
+		if cfg.Password != "" {
+			cfg.Password = "********"
+		}
+		if cfg.Beatsource.Password != "" {
+			cfg.Beatsource.Password = "********"
+		}
+		if cfg.TokenCachePassphrase != "" {
+			cfg.TokenCachePassphrase = "********"
+		}
+		if cfg.Storage.S3.SecretAccessKey != "" {
+			cfg.Storage.S3.SecretAccessKey = "********"
+		}
+		if *sourcesFlag {
+			for _, key := range config.Keys() {
+				fmt.Printf("%s: %s (%s)\n", key, cfg.Value(key), cfg.Source(key))
+			}
+			return
+		}
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitFailure)
+		}
+		fmt.Print(string(data))
+	case "set":
+		if len(args) != 3 {
+			fmt.Println("Usage: beatportdl config set <key> <value>")
+			os.Exit(exitUsage)
+		}
+		if err := cfg.Set(args[1], args[2]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitUsage)
+		}
+		validated := *cfg
+		validated.TagMappings = maps.Clone(cfg.TagMappings)
+		if err := validated.Validate(); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitUsage)
+		}
+		if err := cfg.Save(configFilePath); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitFailure)
+		}
+	case "keyring":
+		fs := flag.NewFlagSet("config keyring", flag.ExitOnError)
+		storeFlag := fs.String("store", string(beatport.StoreBeatport), "Store of the account (beatport, beatsource)")
+		fs.Parse(args[1:])
+
+		if err := cfg.ApplyEnv(); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitUsage)
+		}
+		if err := flags.apply(cfg); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitUsage)
+		}
+		// Beatsource uses the Beatport account when it has none of its own
+		key := "password"
+		switch beatport.Store(*storeFlag) {
+		case beatport.StoreBeatport:
+		case beatport.StoreBeatsource:
+			if cfg.Beatsource.Username != "" {
+				key = "beatsource.password"
+			}
+		default:
+			fmt.Println(ErrUnsupportedLinkStore.Error())
+			os.Exit(exitUsage)
+		}
+		username, password := cfg.Credentials(*storeFlag)
+		if username == "" {
+			fmt.Println("username is not provided")
+			os.Exit(exitUsage)
+		}
+		if password == "" {
+			if !interactive {
+				fmt.Println("password is not provided")
+				os.Exit(exitUsage)
+			}
+			fmt.Print("Password: ")
+			password = GetLine()
+		}
+		if err := keyring.Set(config.KeyringService, username, password); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitFailure)
+		}
+
+		// Save the file as it was loaded, so that only use_keyring changes and the password is removed from where it was read
+		fileConfig.UseKeyring = true
+		switch cfg.Source(key) {
+		case config.SourceFile:
+			fileConfig.Unset("", key)
+		case config.SourceProfile:
+			fileConfig.Unset(flags.profile, key)
+		}
+		if err := fileConfig.Save(configFilePath); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(exitFailure)
+		}
+		fmt.Println("Password stored in the keyring")
+	}
+}
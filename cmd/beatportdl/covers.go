@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/artwork"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+// coverArt are the temp files of a cover: the picture embedded in the
+// tracks and the cover file, which is the same file when both match.
+type coverArt struct {
+	embed string
+	file  string
+}
+
+func (c *coverArt) embedPath() string {
+	if c == nil {
+		return ""
+	}
+	return c.embed
+}
+
+func (c *coverArt) remove() {
+	if c == nil {
+		return
+	}
+	for _, path := range []string{c.embed, c.file} {
+		if path != "" {
+			os.Remove(path)
+		}
+	}
+}
+
+func (app *application) keepCoverFile() bool {
+	return app.config.KeepCover && app.config.SortByContext
+}
+
+// replaceEmbeddedCover reports whether fix_tags replaces the cover embedded
+// in the downloads by the store.
+func (app *application) replaceEmbeddedCover() bool {
+	return app.config.EmbedCoverSize() != config.DefaultCoverSize ||
+		app.config.CoverFormat != artwork.JPEG ||
+		app.embedMaxBytes() > 0
+}
+
+func (app *application) embedMaxBytes() int {
+	// Validated with the config
+	size, _ := config.ParseSize(app.config.CoverEmbedMaxSize)
+	return int(size)
+}
+
+// fetchImage downloads the image to a temp file in the cover format. With
+// maxBytes the image is compressed to fit, as JPEG when it does not fit as is.
+func (app *application) fetchImage(image beatport.Image, size string, maxBytes int) (string, error) {
+	format := app.config.CoverFormat
+	path, err := app.tempFile(artwork.Extension(format))
+	if err != nil {
+		return "", err
+	}
+	if err := app.downloadFile(image.FormattedUrl(size), path, ""); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	converted, err := artwork.Convert(data, format)
+	if err == nil && maxBytes > 0 {
+		converted, err = artwork.Fit(converted, maxBytes)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("convert image: %w", err)
+	}
+	if bytes.Equal(converted, data) {
+		return path, nil
+	}
+
+	// Fit compresses to JPEG whatever the cover format
+	output := strings.TrimSuffix(path, filepath.Ext(path)) + artwork.Extension(artwork.Format(converted))
+	if err := os.WriteFile(output, converted, 0644); err != nil {
+		os.Remove(path)
+		os.Remove(output)
+		return "", err
+	}
+	if output != path {
+		os.Remove(path)
+	}
+	return output, nil
+}
+
+// saveContextImage saves the image of an artist or label to its directory
+// as name and as the folder image, unless it was saved before.
+func (app *application) saveContextImage(image beatport.Image, directory, name string) error {
+	if !app.config.KeepContextImages || !app.config.SortByContext || app.config.DryRun {
+		return nil
+	}
+	if image.URI == "" && image.DynamicURI == "" {
+		return nil
+	}
+	ext := artwork.Extension(app.config.CoverFormat)
+	locations := []string{
+		filepath.Join(directory, name+ext),
+		filepath.Join(directory, "folder"+ext),
+	}
+	if exists, err := app.fileExists(locations[0]); err != nil || exists {
+		return err
+	}
+
+	temp, err := app.fetchImage(image, app.config.FileCoverSize(), 0)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+	for _, location := range locations {
+		if err := copyFile(temp, location); err != nil {
+			return err
+		}
+		if err := app.commitFile(location); err != nil {
+			return err
+		}
+		app.removeStagedFile(location)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+const dedupeDirectoryName = ".beatportdl-store"
+
+var errReflinkUnsupported = errors.New("reflinks are not supported")
+
+type blobState int
+
+const (
+	// blobMissing means the track is not in the store yet
+	blobMissing blobState = iota
+	// blobLinked means the file already is a link to the stored track
+	blobLinked
+	// blobIdentical means the file is a separate copy of the stored track
+	blobIdentical
+	// blobCollision means the file differs from the stored track
+	blobCollision
+)
+
+// dedupeDirectory is the content-addressed store the context directories
+// link to. The default is inside the downloads directory, so that the
+// downloads are moved into it with a rename and can be hardlinked.
+func (app *application) dedupeDirectory() string {
+	if app.config.Dedupe.Directory != "" {
+		return app.config.Dedupe.Directory
+	}
+	return filepath.Join(app.config.DownloadsDirectory, dedupeDirectoryName)
+}
+
+// dedupeBlob returns the store path of a track in a quality, e.g.
+// beatport/1696999/lossless.flac.
+func (app *application) dedupeBlob(store beatport.Store, trackID int64, ext string, kbps int) string {
+	quality := "lossless"
+	if ext != ".flac" {
+		quality = fmt.Sprintf("%dk", kbps)
+	}
+	return filepath.Join(app.dedupeDirectory(), string(store), strconv.FormatInt(trackID, 10), quality+ext)
+}
+
+// lockTrack serializes the workers handling the same track, so that a track
+// in a chart and in its release is downloaded once and linked twice.
+func (app *application) lockTrack(store beatport.Store, trackID int64) func() {
+	value, _ := app.trackLocks.LoadOrStore(fmt.Sprintf("%s/%d", store, trackID), &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// compareToBlob checks an existing file against the stored track.
+func (app *application) compareToBlob(blob, location string) (blobState, error) {
+	blobInfo, err := os.Stat(blob)
+	if errors.Is(err, os.ErrNotExist) {
+		return blobMissing, nil
+	} else if err != nil {
+		return blobMissing, err
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		return blobMissing, err
+	}
+	if os.SameFile(blobInfo, info) {
+		return blobLinked, nil
+	}
+
+	identical, err := sameContent(blob, location, blobInfo.Size(), info.Size())
+	if err != nil {
+		return blobMissing, err
+	}
+	switch {
+	case !identical:
+		return blobCollision, nil
+	case app.config.Dedupe.Link == "reflink":
+		// Reflinked files only share their blocks
+		return blobLinked, nil
+	default:
+		return blobIdentical, nil
+	}
+}
+
+func sameContent(a, b string, sizeA, sizeB int64) (bool, error) {
+	if sizeA != sizeB {
+		return false, nil
+	}
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		n, errA := io.ReadFull(fa, bufA)
+		m, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// linkFile replaces the file at the location with a link to the stored
+// track. Reflinks fall back to a copy on file systems without them.
+func (app *application) linkFile(blob, location string) error {
+	temp, err := app.stagingFile(location)
+	if err != nil {
+		return err
+	}
+
+	switch app.config.Dedupe.Link {
+	case "symlink":
+		err = symlinkFile(blob, location, temp)
+	case "reflink":
+		if err = reflinkFile(blob, temp); errors.Is(err, errReflinkUnsupported) {
+			os.Remove(temp)
+			err = copyFile(blob, temp)
+		}
+	default:
+		err = os.Link(blob, temp)
+	}
+	if err == nil {
+		err = os.Rename(temp, location)
+	}
+	if err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// symlinkFile creates the symlink for the location at the link path. The
+// target is relative to the location, so that the downloads directory can be
+// moved along with the store.
+func symlinkFile(blob, location, link string) error {
+	absBlob, err := filepath.Abs(blob)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(filepath.Dir(location))
+	if err != nil {
+		return err
+	}
+	target, err := filepath.Rel(absDir, absBlob)
+	if err != nil {
+		target = absBlob
+	}
+	return os.Symlink(target, link)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+func TestLinkFile(t *testing.T) {
+	for _, link := range config.SupportedDedupeLinks {
+		t.Run(link, func(t *testing.T) {
+			dir := t.TempDir()
+			app := &application{
+				config: &config.AppConfig{
+					DownloadsDirectory: dir,
+					Dedupe:             config.Dedupe{Enabled: true, Link: link},
+				},
+				state: &state{},
+			}
+
+			blob := app.dedupeBlob(beatport.StoreBeatport, 1696999, ".flac", 900)
+			if blob != filepath.Join(dir, dedupeDirectoryName, "beatport", "1696999", "lossless.flac") {
+				t.Errorf("dedupeBlob() = %s", blob)
+			}
+			if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(blob, []byte("audio"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			release := filepath.Join(dir, "release", "01. Track.flac")
+			chart := filepath.Join(dir, "chart", "Track.flac")
+			if err := os.MkdirAll(filepath.Dir(chart), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(chart, []byte("audio"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if state, err := app.compareToBlob(blob, chart); err != nil || state != blobIdentical && link != "reflink" {
+				t.Errorf("compareToBlob(copy) = %v, %v, want identical", state, err)
+			}
+			for _, location := range []string{release, chart} {
+				if err := app.linkFile(blob, location); err != nil {
+					t.Fatal(err)
+				}
+				if data, err := os.ReadFile(location); err != nil || string(data) != "audio" {
+					t.Errorf("read %s = %q, %v", location, data, err)
+				}
+				if state, err := app.compareToBlob(blob, location); err != nil || state != blobLinked {
+					t.Errorf("compareToBlob(%s) = %v, %v, want linked", location, state, err)
+				}
+			}
+
+			if err := os.Remove(chart); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(chart, []byte("other"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if state, err := app.compareToBlob(blob, chart); err != nil || state != blobCollision {
+				t.Errorf("compareToBlob(other) = %v, %v, want collision", state, err)
+			}
+			if state, err := app.compareToBlob(blob+".missing", chart); err != nil || state != blobMissing {
+				t.Errorf("compareToBlob(missing blob) = %v, %v, want missing", state, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/diskspace"
+)
+
+var ErrDiskFull = errors.New("not enough disk space")
+
+const (
+	// losslessMaxKbps is the bitrate of uncompressed 16 bit stereo audio,
+	// which FLAC files do not exceed
+	losslessMaxKbps = 1411
+	// diskSpaceMargin leaves room for the cover and the tags
+	diskSpaceMargin = 2e6
+	// averageTrackLength is used for the tracks of a job before they are fetched
+	averageTrackLength    = 7 * time.Minute
+	diskSpacePollInterval = 30 * time.Second
+)
+
+// trackSize estimates the most space the track file takes at the bitrate.
+func trackSize(length time.Duration, kbps int) int64 {
+	if kbps == 900 {
+		kbps = losslessMaxKbps
+	}
+	return int64(length/time.Millisecond)*int64(kbps)/8 + diskSpaceMargin
+}
+
+func trackLength(track *beatport.Track) time.Duration {
+	if track.LengthMs <= 0 {
+		return averageTrackLength
+	}
+	return time.Duration(track.LengthMs) * time.Millisecond
+}
+
+// diskSpacePaths are the directories a download to the directory is
+// written to.
+func (app *application) diskSpacePaths(directory string) []string {
+	paths := []string{app.tempDirectory()}
+	if app.config.Storage.Type == "local" {
+		paths = append(paths, directory)
+	}
+	if app.config.Dedupe.Enabled {
+		paths = append(paths, app.dedupeDirectory())
+	}
+	return paths
+}
+
+// checkDiskSpace returns ErrDiskFull when size bytes do not fit on the file
+// system of a path next to the reserve and the downloads in progress.
+func (app *application) checkDiskSpace(size int64, paths ...string) error {
+	reserve, err := config.ParseSize(app.config.DiskSpace.Reserve)
+	if err != nil {
+		return err
+	}
+	needed := uint64(size) + reserve + uint64(app.diskPending.Load())
+	for _, path := range paths {
+		free, err := diskspace.Free(path)
+		if err != nil {
+			return fmt.Errorf("check free space: %w", err)
+		}
+		if needed > free {
+			return fmt.Errorf(
+				"%w in %s: %.1f MB needed, %.1f MB free, %.1f MB reserved",
+				ErrDiskFull, path, float64(size)/1e6, float64(free)/1e6, float64(reserve)/1e6,
+			)
+		}
+	}
+	return nil
+}
+
+// reserveDiskSpace waits until size bytes fit in the directory when the
+// behavior is pause, or stops the run when it is abort. The returned
+// function releases the space once the file is written.
+func (app *application) reserveDiskSpace(url, directory string, size int64) (func(), error) {
+	app.diskMutex.Lock()
+	defer app.diskMutex.Unlock()
+
+	waiting := false
+	for {
+		err := app.checkDiskSpace(size, app.diskSpacePaths(directory)...)
+		if err == nil {
+			if waiting {
+				app.infoLogWrapper(url, "disk space available, resuming")
+			}
+			app.diskPending.Add(size)
+			return func() {
+				app.diskPending.Add(-size)
+			}, nil
+		}
+		if !errors.Is(err, ErrDiskFull) {
+			// The check is best effort, the download fails on its own when the disk is full
+			app.logger.Debug("skip disk space check", "error", err)
+			return func() {}, nil
+		}
+		if app.config.DiskSpace.WhenFull != "pause" {
+			app.stopDiskFull()
+			return nil, err
+		}
+
+		if !waiting {
+			app.logger.Warn(
+				fmt.Sprintf("waiting for disk space, checking again every %s", diskSpacePollInterval),
+				append(urlAttrs(url), "error", err)...,
+			)
+			waiting = true
+		}
+		select {
+		case <-app.ctx.Done():
+			return nil, err
+		case <-time.After(diskSpacePollInterval):
+		}
+	}
+}
+
+// checkJobSpace checks that a job of trackCount tracks can start, and warns
+// when it will probably not fit. The job is not refused for its full size
+// since the tracks that already exist take no space.
+func (app *application) checkJobSpace(url, directory string, trackCount int) error {
+	if app.config.DryRun {
+		return nil
+	}
+	_, _, kbps := plannedQuality(app.config.Quality)
+	release, err := app.reserveDiskSpace(url, directory, trackSize(averageTrackLength, kbps))
+	if err != nil {
+		return err
+	}
+	release()
+
+	estimate := int64(trackCount) * trackSize(averageTrackLength, kbps)
+	if err := app.checkDiskSpace(estimate, app.diskSpacePaths(directory)...); errors.Is(err, ErrDiskFull) {
+		app.logger.Warn(
+			fmt.Sprintf("the %d tracks may not fit on the disk", trackCount),
+			append(urlAttrs(url), "error", err)...,
+		)
+	}
+	return nil
+}
+
+// stopDiskFull cancels the run, letting the downloads in progress finish.
+func (app *application) stopDiskFull() {
+	if !app.diskFull.Swap(true) {
+		app.LogInfo("The disk is full. Waiting for download workers to finish")
+	}
+	if app.cancel != nil {
+		app.cancel()
+	}
+}
+
+// isDiskFull reports whether the error is caused by a full disk, found by
+// the checks or by a failed write.
+func isDiskFull(err error) bool {
+	return errors.Is(err, ErrDiskFull) || errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"unspok3n/beatportdl/config"
+)
+
+func TestReserveDiskSpace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := &application{
+		config: &config.AppConfig{
+			DownloadsDirectory: t.TempDir(),
+			Storage:            config.Storage{Type: "local"},
+			DiskSpace:          config.DiskSpace{Reserve: "0", WhenFull: "abort"},
+		},
+		state: &state{
+			ctx:    ctx,
+			cancel: cancel,
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+	}
+
+	release, err := app.reserveDiskSpace("", app.config.DownloadsDirectory, 1e6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := app.diskPending.Load(); pending != 1e6 {
+		t.Errorf("pending = %d, want 1000000", pending)
+	}
+	release()
+	if pending := app.diskPending.Load(); pending != 0 {
+		t.Errorf("pending after release = %d, want 0", pending)
+	}
+
+	app.config.DiskSpace.Reserve = "1000000TB"
+	if _, err := app.reserveDiskSpace("", app.config.DownloadsDirectory, 1e6); !errors.Is(err, ErrDiskFull) {
+		t.Errorf("reserveDiskSpace() = %v, want ErrDiskFull", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("the run was not stopped")
+	}
+}
@@ -3,27 +3,55 @@ package main
 import (
 	"errors"
 	"fmt"
-	"github.com/google/uuid"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
-	"unspok3n/beatportdl/config"
+	"sync/atomic"
+	"time"
+	"unspok3n/beatportdl/internal/artwork"
 	"unspok3n/beatportdl/internal/beatport"
 	"unspok3n/beatportdl/internal/taglib"
+	"unspok3n/beatportdl/internal/verify"
 )
 
 func (app *application) errorLogWrapper(url, step string, err error) {
-	app.LogError(fmt.Sprintf("[%s] %s", url, step), err)
+	app.addFailedResult(url, step, err)
+	if isDiskFull(err) && app.config.DiskSpace.WhenFull == "abort" {
+		app.stopDiskFull()
+		if app.diskFullLogged.Swap(true) {
+			// The first error tells why the run stops, the others are only counted
+			app.failed.Add(1)
+			return
+		}
+	}
+	app.LogError(step, err, append(urlAttrs(url), "step", step)...)
 }
 
 func (app *application) infoLogWrapper(url, message string) {
-	app.LogInfo(fmt.Sprintf("[%s] %s", url, message))
+	app.logger.Info(message, urlAttrs(url)...)
+}
+
+// urlAttrs returns the log attributes of a store URL.
+func urlAttrs(url string) []any {
+	attrs := []any{"url", url}
+	link, err := (&beatport.Beatport{}).ParseUrl(url)
+	if err != nil {
+		return attrs
+	}
+	attrs = append(attrs, "store", link.Store)
+	if link.Type == beatport.TrackLink {
+		attrs = append(attrs, "track_id", link.ID)
+	}
+	return attrs
 }
 
 func (app *application) createDirectory(baseDir string, subDir ...string) (string, error) {
 	fullPath := filepath.Join(baseDir, filepath.Join(subDir...))
+	if app.config.DryRun {
+		return fullPath, nil
+	}
 	err := CreateDirectory(fullPath)
 	return fullPath, err
 }
@@ -41,19 +69,21 @@ func (app *application) setupDownloadsDirectory(baseDir string, entity Downloads
 				beatport.NamingPreferences{
 					Template:           app.config.ReleaseDirectoryTemplate,
 					Whitespace:         app.config.WhitespaceCharacter,
+					Path:               app.pathProfile(),
 					ArtistsLimit:       app.config.ArtistsLimit,
 					ArtistsShortForm:   app.config.ArtistsShortForm,
 					TrackNumberPadding: app.config.TrackNumberPadding,
 				},
 			)
 			if app.config.SortByLabel && entity != nil {
-				baseDir = filepath.Join(baseDir, castedEntity.Label.Name)
+				baseDir = filepath.Join(baseDir, app.pathProfile().SanitizePath(castedEntity.Label.Name, ""))
 			}
 		case *beatport.Playlist:
 			subDir = castedEntity.DirectoryName(
 				beatport.NamingPreferences{
 					Template:           app.config.PlaylistDirectoryTemplate,
 					Whitespace:         app.config.WhitespaceCharacter,
+					Path:               app.pathProfile(),
 					TrackNumberPadding: app.config.TrackNumberPadding,
 				},
 			)
@@ -62,6 +92,7 @@ func (app *application) setupDownloadsDirectory(baseDir string, entity Downloads
 				beatport.NamingPreferences{
 					Template:           app.config.ChartDirectoryTemplate,
 					Whitespace:         app.config.WhitespaceCharacter,
+					Path:               app.pathProfile(),
 					TrackNumberPadding: app.config.TrackNumberPadding,
 				},
 			)
@@ -70,6 +101,7 @@ func (app *application) setupDownloadsDirectory(baseDir string, entity Downloads
 				beatport.NamingPreferences{
 					Template:   app.config.LabelDirectoryTemplate,
 					Whitespace: app.config.WhitespaceCharacter,
+					Path:       app.pathProfile(),
 				},
 			)
 		case *beatport.Artist:
@@ -77,6 +109,15 @@ func (app *application) setupDownloadsDirectory(baseDir string, entity Downloads
 				beatport.NamingPreferences{
 					Template:   app.config.ArtistDirectoryTemplate,
 					Whitespace: app.config.WhitespaceCharacter,
+					Path:       app.pathProfile(),
+				},
+			)
+		case *beatport.GenreFeed:
+			subDir = castedEntity.DirectoryName(
+				beatport.NamingPreferences{
+					Template:   app.config.GenreDirectoryTemplate,
+					Whitespace: app.config.WhitespaceCharacter,
+					Path:       app.pathProfile(),
 				},
 			)
 		}
@@ -87,33 +128,60 @@ func (app *application) setupDownloadsDirectory(baseDir string, entity Downloads
 
 func (app *application) requireCover(respectFixTags, respectKeepCover bool) bool {
 	fixTags := respectFixTags && app.config.FixTags &&
-		(app.config.CoverSize != config.DefaultCoverSize || app.config.Quality != "lossless")
-	keepCover := respectKeepCover && app.config.SortByContext && app.config.KeepCover
+		(app.replaceEmbeddedCover() || app.config.Quality != "lossless")
+	keepCover := respectKeepCover && app.keepCoverFile()
 	return fixTags || keepCover
 }
 
-func (app *application) downloadCover(image beatport.Image, downloadsDir string) (string, error) {
-	coverUrl := image.FormattedUrl(app.config.CoverSize)
-	coverPath := filepath.Join(downloadsDir, uuid.New().String())
-	err := app.downloadFile(coverUrl, coverPath, "")
-	if err != nil {
-		os.Remove(coverPath)
-		return "", err
+// downloadCover downloads the picture embedded by fix_tags, and the cover
+// file when it is kept, sharing one file when both are the same.
+func (app *application) downloadCover(image beatport.Image, respectFixTags, respectKeepCover bool) (*coverArt, error) {
+	if app.config.DryRun {
+		return nil, nil
 	}
-	return coverPath, nil
+	cover := &coverArt{}
+	var err error
+	if respectKeepCover && app.keepCoverFile() {
+		if cover.file, err = app.fetchImage(image, app.config.FileCoverSize(), 0); err != nil {
+			return nil, err
+		}
+	}
+	if respectFixTags && app.config.FixTags {
+		maxBytes := app.embedMaxBytes()
+		if cover.file != "" && maxBytes == 0 && app.config.EmbedCoverSize() == app.config.FileCoverSize() {
+			cover.embed = cover.file
+		} else if cover.embed, err = app.fetchImage(image, app.config.EmbedCoverSize(), maxBytes); err != nil {
+			cover.remove()
+			return nil, err
+		}
+	}
+	return cover, nil
 }
 
-func (app *application) handleCoverFile(path string) error {
-	if path == "" {
+// handleCoverFile moves the downloaded cover to the directory when the
+// covers are kept, and removes it otherwise.
+func (app *application) handleCoverFile(cover *coverArt, directory string) error {
+	if cover == nil {
 		return nil
 	}
-	if app.config.KeepCover && app.config.SortByContext {
-		newPath := filepath.Dir(path) + "/cover.jpg"
-		if err := os.Rename(path, newPath); err != nil {
+	if cover.embed != cover.file {
+		os.Remove(cover.embed)
+	}
+	if cover.file == "" {
+		return nil
+	}
+	if app.keepCoverFile() {
+		newPath := filepath.Join(directory, "cover"+filepath.Ext(cover.file))
+		if err := os.Rename(cover.file, newPath); err != nil {
+			cover.remove()
 			return err
 		}
+		if err := app.commitFile(newPath); err != nil {
+			return err
+		}
+		app.removeStagedFile(newPath)
 	} else {
-		os.Remove(path)
+		os.Remove(cover.file)
 	}
 	return nil
 }
@@ -122,55 +190,106 @@ var (
 	ErrTrackFileExists = errors.New("file already exists")
 )
 
-func (app *application) saveTrack(inst *beatport.Beatport, track *beatport.Track, directory string, quality string) (string, error) {
+func (app *application) pathProfile() beatport.PathProfile {
+	return beatport.PathProfile{
+		Name:          app.config.PathProfile,
+		Normalization: app.config.PathNormalization,
+		ASCII:         app.config.PathASCII,
+	}
+}
+
+func (app *application) trackFilename(track *beatport.Track) string {
+	return track.Filename(
+		beatport.NamingPreferences{
+			Template:           app.config.TrackFileTemplate,
+			Whitespace:         app.config.WhitespaceCharacter,
+			Path:               app.pathProfile(),
+			ArtistsLimit:       app.config.ArtistsLimit,
+			ArtistsShortForm:   app.config.ArtistsShortForm,
+			TrackNumberPadding: app.config.TrackNumberPadding,
+			KeySystem:          app.config.KeySystem,
+		},
+	)
+}
+
+// trackPath returns the path of the track file in the directory, cut to the
+// path length limit of the path profile.
+func (app *application) trackPath(directory string, track *beatport.Track, ext string) string {
+	return filepath.Join(directory, app.pathProfile().FitPath(directory, app.trackFilename(track), ext)+ext)
+}
+
+// savedTrack is a track written to a temp file, which is moved to its
+// location once it is tagged.
+type savedTrack struct {
+	location string
+	temp     string
+	// blob is the stored copy the location is linked to when dedupe is enabled
+	blob string
+	// retagBlob updates the tags of the stored copy in place, so that its
+	// hardlinks keep sharing it
+	retagBlob bool
+	status    resultStatus
+	quality   string
+}
+
+// streamQuality returns the file extension, display quality and approximate
+// bitrate in kbps of a track download.
+func streamQuality(quality string) (string, string, int, error) {
+	switch quality {
+	case ".128k.aac.mp4":
+		return ".m4a", "AAC 128kbps", 128, nil
+	case ".256k.aac.mp4":
+		return ".m4a", "AAC 256kbps", 256, nil
+	case ".flac":
+		return ".flac", "FLAC", 900, nil
+	default:
+		return "", "", 0, fmt.Errorf("invalid stream quality: %s", quality)
+	}
+}
+
+func (app *application) saveTrack(inst *beatport.Beatport, track *beatport.Track, directory string, quality string) (*savedTrack, error) {
 	var fileExtension string
 	var displayQuality string
+	var kbps int
 
 	var stream *beatport.TrackStream
 	var download *beatport.TrackDownload
 
-	switch app.config.Quality {
-	case "medium-hls":
+	switch {
+	case app.config.DryRun:
+		fileExtension, displayQuality, kbps = plannedQuality(quality)
+	case app.config.Quality == "medium-hls":
 		trackStream, err := inst.StreamTrack(track.ID)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		fileExtension = ".m4a"
 		displayQuality = "AAC 128kbps - HLS"
+		kbps = 128
 		stream = trackStream
 	default:
 		trackDownload, err := inst.DownloadTrack(track.ID, quality)
 		if err != nil {
-			return "", err
-		}
-		switch trackDownload.StreamQuality {
-		case ".128k.aac.mp4":
-			fileExtension = ".m4a"
-			displayQuality = "AAC 128kbps"
-		case ".256k.aac.mp4":
-			fileExtension = ".m4a"
-			displayQuality = "AAC 256kbps"
-		case ".flac":
-			fileExtension = ".flac"
-			displayQuality = "FLAC"
-		default:
-			return "", fmt.Errorf("invalid stream quality: %s", trackDownload.StreamQuality)
+			return nil, err
+		}
+		fileExtension, displayQuality, kbps, err = streamQuality(trackDownload.StreamQuality)
+		if err != nil {
+			return nil, err
 		}
 		download = trackDownload
 	}
 
-	fileName := track.Filename(
-		beatport.NamingPreferences{
-			Template:           app.config.TrackFileTemplate,
-			Whitespace:         app.config.WhitespaceCharacter,
-			ArtistsLimit:       app.config.ArtistsLimit,
-			ArtistsShortForm:   app.config.ArtistsShortForm,
-			TrackNumberPadding: app.config.TrackNumberPadding,
-			KeySystem:          app.config.KeySystem,
-		},
-	)
+	fileName := app.pathProfile().FitPath(directory, app.trackFilename(track), fileExtension)
 	filePath := fmt.Sprintf("%s/%s%s", directory, fileName, fileExtension)
-	if _, err := os.Stat(filePath); err == nil {
+	var blob string
+	if app.config.Dedupe.Enabled {
+		blob = app.dedupeBlob(inst.Store(), track.ID, fileExtension, kbps)
+	}
+	exists, err := app.fileExists(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("check file: %w", err)
+	}
+	if exists {
 		app.activeFilesMutex.RLock()
 		_, exists := app.activeFiles[filePath]
 		app.activeFilesMutex.RUnlock()
@@ -179,20 +298,57 @@ func (app *application) saveTrack(inst *beatport.Beatport, track *beatport.Track
 			i := 1
 			for {
 				filePath = fmt.Sprintf("%s/%s (%d)%s", directory, fileName, i, fileExtension)
-				if _, err := os.Stat(filePath); os.IsNotExist(err) {
+				if exists, err := app.fileExists(filePath); err != nil {
+					return nil, fmt.Errorf("check file: %w", err)
+				} else if !exists {
 					break
 				}
 				i++
 			}
 		} else {
+			updateBlob := blob
+			blobExists := false
+			if blob != "" {
+				state, err := app.compareToBlob(blob, filePath)
+				if err != nil {
+					return nil, fmt.Errorf("compare stored file: %w", err)
+				}
+				blobExists = state == blobLinked || state == blobIdentical
+				switch {
+				case state == blobIdentical && app.config.TrackExists != "update" && app.config.TrackExists != "overwrite":
+					return &savedTrack{location: filePath, blob: blob, status: resultLinked, quality: displayQuality}, nil
+				case state == blobCollision:
+					app.logger.Warn(
+						"file differs from the stored download",
+						append(urlAttrs(track.StoreUrl()), "path", filePath, "stored", blob)...,
+					)
+					// The tags of the file are updated in place, the stored copy is left alone
+					updateBlob = ""
+				}
+			}
 			switch app.config.TrackExists {
 			case "skip":
-				return "", nil
+				return &savedTrack{status: resultSkipped, quality: displayQuality}, nil
 			case "update":
 				app.infoLogWrapper(track.StoreUrl(), "updating tags")
-				return filePath, nil
+				saved := &savedTrack{location: filePath, blob: updateBlob, status: resultUpdated, quality: displayQuality}
+				if app.config.DryRun {
+					return saved, nil
+				}
+				if updateBlob != "" && blobExists {
+					saved.retagBlob = true
+					return saved, nil
+				}
+				if saved.temp, err = app.tempFile(fileExtension); err != nil {
+					return nil, err
+				}
+				if err := app.stageFile(filePath, saved.temp); err != nil {
+					os.Remove(saved.temp)
+					return nil, fmt.Errorf("stage file: %w", err)
+				}
+				return saved, nil
 			case "error":
-				return "", ErrTrackFileExists
+				return nil, ErrTrackFileExists
 			}
 		}
 	}
@@ -201,6 +357,25 @@ func (app *application) saveTrack(inst *beatport.Beatport, track *beatport.Track
 	app.activeFiles[filePath] = struct{}{}
 	app.activeFilesMutex.Unlock()
 
+	saved := &savedTrack{location: filePath, blob: blob, status: resultDownloaded, quality: displayQuality}
+	if blob != "" {
+		if _, err := os.Stat(blob); err == nil {
+			saved.status = resultLinked
+			return saved, nil
+		}
+	}
+	if app.config.DryRun {
+		return saved, nil
+	}
+	release, err := app.reserveDiskSpace(track.StoreUrl(), directory, trackSize(trackLength(track), kbps))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if saved.temp, err = app.tempFile(fileExtension); err != nil {
+		return nil, err
+	}
+
 	var prefix string
 	infoDisplay := fmt.Sprintf("%s (%s) [%s]", track.Name.String(), track.MixName.String(), displayQuality)
 	if app.config.ShowProgress {
@@ -209,32 +384,55 @@ func (app *application) saveTrack(inst *beatport.Beatport, track *beatport.Track
 		fmt.Println("Downloading " + infoDisplay)
 	}
 
-	if download != nil {
-		if err := app.downloadFile(download.Location, filePath, prefix); err != nil {
-			os.Remove(filePath)
-			return "", err
-		}
-	} else if stream != nil {
-		segments, key, err := getStreamSegments(stream.Url)
-		if err != nil {
-			return "", fmt.Errorf("get stream segments: %v", err)
-		}
-		segmentsFile, err := app.downloadSegments(directory, *segments, *key, prefix)
-		defer os.Remove(segmentsFile)
-		if err != nil {
-			return "", fmt.Errorf("download segments: %v", err)
-		}
-		if err := remuxToM4A(segmentsFile, filePath); err != nil {
-			os.Remove(filePath)
-			return "", fmt.Errorf("remux to m4a: %v", err)
-		}
+	if err := app.fetchVerifiedTrackFile(track, download, stream, saved.temp, prefix); err != nil {
+		return nil, err
 	}
 
 	if !app.config.ShowProgress {
 		fmt.Printf("Finished downloading %s\n", infoDisplay)
 	}
 
-	return filePath, nil
+	return saved, nil
+}
+
+// fetchVerifiedTrackFile downloads the track to the temp file, downloading
+// it again when the file fails verification.
+func (app *application) fetchVerifiedTrackFile(track *beatport.Track, download *beatport.TrackDownload, stream *beatport.TrackStream, temp, prefix string) error {
+	for attempt := 1; ; attempt++ {
+		err := app.fetchTrackFile(download, stream, temp, prefix)
+		if err == nil {
+			err = app.verifyTrackFile(temp, track)
+			if errors.Is(err, verify.ErrCorrupt) && attempt < verifyAttempts {
+				app.logger.Warn("downloaded file is corrupt, retrying", "url", track.StoreUrl(), "attempt", attempt, "error", err)
+				os.Remove(temp)
+				continue
+			}
+		}
+		if err != nil {
+			os.Remove(temp)
+		}
+		return err
+	}
+}
+
+// fetchTrackFile downloads the track file or the stream segments to the temp file.
+func (app *application) fetchTrackFile(download *beatport.TrackDownload, stream *beatport.TrackStream, temp, prefix string) error {
+	if download != nil {
+		return app.downloadFile(download.Location, temp, prefix)
+	}
+	segments, key, err := getStreamSegments(stream.Url)
+	if err != nil {
+		return fmt.Errorf("get stream segments: %v", err)
+	}
+	segmentsFile, err := app.downloadSegments(app.tempDirectory(), *segments, *key, prefix)
+	if err != nil {
+		return fmt.Errorf("download segments: %v", err)
+	}
+	defer os.Remove(segmentsFile)
+	if err := remuxToM4A(segmentsFile, temp); err != nil {
+		return fmt.Errorf("remux to m4a: %v", err)
+	}
+	return nil
 }
 
 const (
@@ -356,13 +554,13 @@ func (app *application) tagTrack(location string, track *beatport.Track, coverPa
 		}
 	}
 
-	if coverPath != "" && (app.config.CoverSize != config.DefaultCoverSize || fileExt == ".m4a") {
+	if coverPath != "" && (app.replaceEmbeddedCover() || fileExt == ".m4a") {
 		data, err := os.ReadFile(coverPath)
 		if err != nil {
 			return err
 		}
 		picture := taglib.Picture{
-			MimeType:    "image/jpeg",
+			MimeType:    artwork.MimeType(artwork.Format(data)),
 			PictureType: "Front",
 			Description: "Cover",
 			Data:        data,
@@ -380,21 +578,74 @@ func (app *application) tagTrack(location string, track *beatport.Track, coverPa
 	return nil
 }
 
-func (app *application) handleTrack(inst *beatport.Beatport, track *beatport.Track, downloadsDir string, coverPath string) error {
-	location, err := app.saveTrack(inst, track, downloadsDir, app.config.Quality)
+func (app *application) handleTrack(inst *beatport.Beatport, track *beatport.Track, downloadsDir string, coverPath string) (string, error) {
+	start := time.Now()
+	if app.config.Dedupe.Enabled {
+		defer app.lockTrack(inst.Store(), track.ID)()
+	}
+	saved, err := app.saveTrack(inst, track, downloadsDir, app.config.Quality)
 	if err != nil {
-		return fmt.Errorf("save track: %v", err)
+		return "", fmt.Errorf("save track: %v", err)
 	}
-	if err = app.tagTrack(location, track, coverPath); err != nil && location != "" {
-		return fmt.Errorf("tag track: %v", err)
+	location, status, quality := saved.location, saved.status, saved.quality
+	if app.config.DryRun {
+		app.planTrack(inst, track, status, quality, location)
+		return location, nil
 	}
-	return nil
+	if saved.retagBlob {
+		if err = app.tagTrack(saved.blob, track, coverPath); err != nil {
+			return "", fmt.Errorf("tag stored track: %v", err)
+		}
+	}
+	if saved.temp != "" {
+		defer os.Remove(saved.temp)
+		if err = app.tagTrack(saved.temp, track, coverPath); err != nil {
+			return "", fmt.Errorf("tag track: %v", err)
+		}
+		target := location
+		if saved.blob != "" {
+			target = saved.blob
+		}
+		if err = moveIntoPlace(saved.temp, target); err != nil {
+			return "", fmt.Errorf("move track: %w", err)
+		}
+	}
+	if saved.blob != "" {
+		if err = app.linkFile(saved.blob, location); err != nil {
+			return "", fmt.Errorf("link track: %w", err)
+		}
+	}
+	if err = app.commitFile(location); err != nil {
+		return "", fmt.Errorf("commit file: %w", err)
+	}
+	defer app.removeStagedFile(location)
+	app.succeeded.Add(1)
+	app.addTrackResult(inst, track, status, quality, location)
+	if status != resultSkipped {
+		app.runTrackHooks(inst, track, status, quality, location)
+	}
+	app.logger.Debug(
+		"track downloaded",
+		"url", track.StoreUrl(),
+		"store", inst.Store(),
+		"track_id", track.ID,
+		"duration", time.Since(start),
+	)
+	return location, nil
 }
 
 func (app *application) cleanup(downloadsDir string) {
 	if downloadsDir != app.config.DownloadsDirectory {
 		os.Remove(downloadsDir)
 	}
+	if !app.storage.IsLocal() {
+		// The staged files are removed once committed, so the context directories are empty
+		for dir := filepath.Dir(downloadsDir); dir != app.config.DownloadsDirectory && strings.HasPrefix(dir, app.config.DownloadsDirectory); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
 }
 
 func ForPaginated[T any](
@@ -404,16 +655,18 @@ func ForPaginated[T any](
 	processItem func(item T, i int) error,
 ) error {
 	page := 1
+	index := 0
 	for {
 		paginated, err := fetchPage(entityId, page, params)
 		if err != nil {
 			return fmt.Errorf("fetch page: %w", err)
 		}
 
-		for i, item := range paginated.Results {
-			if err := processItem(item, i); err != nil {
+		for _, item := range paginated.Results {
+			if err := processItem(item, index); err != nil {
 				return fmt.Errorf("process item: %w", err)
 			}
+			index++
 		}
 
 		if paginated.Next == nil {
@@ -424,24 +677,46 @@ func ForPaginated[T any](
 	return nil
 }
 
+func (app *application) storeInstance(store beatport.Store) (*beatport.Beatport, error) {
+	switch store {
+	case beatport.StoreBeatport:
+		return app.bp, nil
+	case beatport.StoreBeatsource:
+		return app.bs, nil
+	default:
+		return nil, ErrUnsupportedLinkStore
+	}
+}
+
 func (app *application) handleUrl(url string) {
+	if profile, profileUrl, found := cutProfilePrefix(url); found {
+		profileApp, err := app.profileApplication(profile)
+		if err != nil {
+			app.errorLogWrapper(url, "load profile", err)
+			return
+		}
+		profileApp.handleUrl(profileUrl)
+		return
+	}
+
 	link, err := app.bp.ParseUrl(url)
 	if err != nil {
 		app.errorLogWrapper(url, "parse url", err)
 		return
 	}
 
-	var inst *beatport.Beatport
-	switch link.Store {
-	case beatport.StoreBeatport:
-		inst = app.bp
-	case beatport.StoreBeatsource:
-		inst = app.bs
-	default:
-		app.LogError("handle URL", ErrUnsupportedLinkStore)
+	inst, err := app.storeInstance(link.Store)
+	if err != nil {
+		app.errorLogWrapper(url, "handle URL", err)
 		return
 	}
 
+	start := time.Now()
+	defer func() {
+		app.logger.Debug("url handled", "url", url, "store", link.Store, "duration", time.Since(start))
+		app.runURLHooks(link, time.Since(start))
+	}()
+
 	switch link.Type {
 	case beatport.TrackLink:
 		app.handleTrackLink(inst, link)
@@ -455,6 +730,8 @@ func (app *application) handleUrl(url string) {
 		app.handleLabelLink(inst, link)
 	case beatport.ArtistLink:
 		app.handleArtistLink(inst, link)
+	case beatport.GenreTopLink, beatport.GenreHypeLink, beatport.GenreReleasesLink:
+		app.handleGenreFeedLink(inst, link)
 	default:
 		app.LogError("handle URL", ErrUnsupportedLinkType)
 	}
@@ -482,21 +759,21 @@ func (app *application) handleTrackLink(inst *beatport.Beatport, link *beatport.
 
 	wg := sync.WaitGroup{}
 	app.downloadWorker(&wg, func() {
-		var cover string
+		var cover *coverArt
 		if app.requireCover(true, true) {
-			cover, err = app.downloadCover(track.Release.Image, downloadsDir)
+			cover, err = app.downloadCover(track.Release.Image, true, true)
 			if err != nil {
 				app.errorLogWrapper(link.Original, "download track release cover", err)
 			}
 		}
 
-		if err := app.handleTrack(inst, track, downloadsDir, cover); err != nil {
+		if _, err := app.handleTrack(inst, track, downloadsDir, cover.embedPath()); err != nil {
 			app.errorLogWrapper(link.Original, "handle track", err)
-			os.Remove(cover)
+			cover.remove()
 			return
 		}
 
-		if err := app.handleCoverFile(cover); err != nil {
+		if err := app.handleCoverFile(cover, downloadsDir); err != nil {
 			app.errorLogWrapper(link.Original, "handle cover file", err)
 			return
 		}
@@ -519,10 +796,15 @@ func (app *application) handleReleaseLink(inst *beatport.Beatport, link *beatpor
 		return
 	}
 
-	var cover string
+	if err := app.checkJobSpace(link.Original, downloadsDir, len(release.TrackUrls)); err != nil {
+		app.errorLogWrapper(link.Original, "check disk space", err)
+		return
+	}
+
+	var cover *coverArt
 	if app.requireCover(true, true) {
 		app.semAcquire(app.downloadSem)
-		cover, err = app.downloadCover(release.Image, downloadsDir)
+		cover, err = app.downloadCover(release.Image, true, true)
 		if err != nil {
 			app.errorLogWrapper(link.Original, "download release cover", err)
 		}
@@ -546,7 +828,7 @@ func (app *application) handleReleaseLink(inst *beatport.Beatport, link *beatpor
 			trackStoreUrl := track.StoreUrl()
 			track.Release = *release
 
-			if err := app.handleTrack(inst, track, downloadsDir, cover); err != nil {
+			if _, err := app.handleTrack(inst, track, downloadsDir, cover.embedPath()); err != nil {
 				app.errorLogWrapper(trackStoreUrl, "handle track", err)
 				return
 			}
@@ -554,7 +836,7 @@ func (app *application) handleReleaseLink(inst *beatport.Beatport, link *beatpor
 	}
 	wg.Wait()
 
-	if err := app.handleCoverFile(cover); err != nil {
+	if err := app.handleCoverFile(cover, downloadsDir); err != nil {
 		app.errorLogWrapper(link.Original, "handle cover file", err)
 		return
 	}
@@ -575,58 +857,29 @@ func (app *application) handlePlaylistLink(inst *beatport.Beatport, link *beatpo
 		return
 	}
 
+	if err := app.checkJobSpace(link.Original, downloadsDir, playlist.TrackCount); err != nil {
+		app.errorLogWrapper(link.Original, "check disk space", err)
+		return
+	}
+
+	var syncState *contextSync
+	if app.config.PlaylistSync {
+		syncState, err = app.newContextSync(link, downloadsDir)
+		if err != nil {
+			app.errorLogWrapper(link.Original, "load sync manifest", err)
+			return
+		}
+	}
+
 	wg := sync.WaitGroup{}
 	err = ForPaginated[beatport.PlaylistItem](link.ID, "", inst.GetPlaylistItems, func(item beatport.PlaylistItem, i int) error {
+		if syncState != nil {
+			syncState.list(item.Track.ID)
+		}
 		app.downloadWorker(&wg, func() {
-			trackStoreUrl := item.Track.StoreUrl()
-
-			release, err := inst.GetRelease(item.Track.Release.ID)
-			if err != nil {
-				app.errorLogWrapper(trackStoreUrl, "fetch track release", err)
-				return
-			}
-			item.Track.Release = *release
-
-			trackDownloadsDir := downloadsDir
-			trackFull, err := inst.GetTrack(item.Track.ID)
-			if err != nil {
-				app.errorLogWrapper(trackStoreUrl, "fetch full track", err)
-				return
-			}
-			item.Track.Number = trackFull.Number
-			if app.config.SortByContext && app.config.ForceReleaseDirectories {
-				trackDownloadsDir, err = app.setupDownloadsDirectory(downloadsDir, release)
-				if err != nil {
-					app.errorLogWrapper(trackStoreUrl, "setup track release directory", err)
-					return
-				}
-			}
-
-			var cover string
-			if app.requireCover(true, app.config.ForceReleaseDirectories) {
-				cover, err = app.downloadCover(item.Track.Release.Image, trackDownloadsDir)
-				if err != nil {
-					app.errorLogWrapper(trackStoreUrl, "download track release cover", err)
-				} else if !app.config.ForceReleaseDirectories {
-					defer os.Remove(cover)
-				}
-			}
-
-			if err := app.handleTrack(inst, &item.Track, trackDownloadsDir, cover); err != nil {
-				app.errorLogWrapper(trackStoreUrl, "handle track", err)
-				os.Remove(cover)
-				app.cleanup(trackDownloadsDir)
-				return
-			}
-
-			if app.config.ForceReleaseDirectories {
-				if err := app.handleCoverFile(cover); err != nil {
-					app.errorLogWrapper(trackStoreUrl, "handle track release cover file", err)
-					return
-				}
-			}
-
-			app.cleanup(trackDownloadsDir)
+			item.Track.Position = item.Position
+			item.Track.PositionTotal = playlist.TrackCount
+			app.handleContextTrack(inst, &item.Track, downloadsDir, syncState)
 		})
 		return nil
 	})
@@ -637,6 +890,12 @@ func (app *application) handlePlaylistLink(inst *beatport.Beatport, link *beatpo
 	}
 
 	wg.Wait()
+
+	if syncState != nil {
+		if err := app.finishContextSync(syncState); err != nil {
+			app.errorLogWrapper(link.Original, "finish playlist sync", err)
+		}
+	}
 }
 
 func (app *application) handleChartLink(inst *beatport.Beatport, link *beatport.Link) {
@@ -651,82 +910,191 @@ func (app *application) handleChartLink(inst *beatport.Beatport, link *beatport.
 		app.errorLogWrapper(link.Original, "setup downloads directory", err)
 		return
 	}
+
+	app.handleContextTracks(inst, link, downloadsDir, &chart.Image, chart.TrackCount, inst.GetChartTracks)
+}
+
+func (app *application) handleGenreFeedLink(inst *beatport.Beatport, link *beatport.Link) {
+	feed, err := inst.GetGenreFeed(link)
+	if err != nil {
+		app.errorLogWrapper(link.Original, "fetch genre", err)
+		return
+	}
+
+	downloadsDir, err := app.setupDownloadsDirectory(app.config.DownloadsDirectory, feed)
+	if err != nil {
+		app.errorLogWrapper(link.Original, "setup downloads directory", err)
+		return
+	}
+
+	switch link.Type {
+	case beatport.GenreTopLink:
+		app.handleContextTracks(inst, link, downloadsDir, nil, 100, inst.GetGenreTopTracks)
+	case beatport.GenreHypeLink:
+		app.handleContextTracks(inst, link, downloadsDir, nil, 100, inst.GetGenreHypeTracks)
+	case beatport.GenreReleasesLink:
+		fetchPage := inst.GetGenreReleases
+		if app.filter.DateFrom == "" {
+			// The feed goes back to the beginning of the catalog,
+			// so without a date window only the latest page is downloaded
+			fetchPage = func(id int64, page int, params string) (*beatport.Paginated[beatport.Release], error) {
+				releases, err := inst.GetGenreReleases(id, page, params)
+				if releases != nil {
+					releases.Next = nil
+				}
+				return releases, err
+			}
+		}
+		params := joinParams(link.Params, app.filter.ReleaseParams())
+		err = ForPaginated[beatport.Release](link.ID, params, fetchPage, func(release beatport.Release, i int) error {
+			if !app.filter.MatchRelease(&release) {
+				return nil
+			}
+			app.globalWorker(func() {
+				app.handleContextRelease(inst, release, downloadsDir)
+			})
+			return nil
+		})
+		if err != nil {
+			app.errorLogWrapper(link.Original, "handle genre releases", err)
+		}
+	}
+}
+
+// handleContextTracks downloads a chart-like track listing into downloadsDir,
+// keeping the position of each track in the listing.
+func (app *application) handleContextTracks(
+	inst *beatport.Beatport,
+	link *beatport.Link,
+	downloadsDir string,
+	image *beatport.Image,
+	trackCount int,
+	fetchPage func(id int64, page int, params string) (*beatport.Paginated[beatport.Track], error),
+) {
+	if err := app.checkJobSpace(link.Original, downloadsDir, trackCount); err != nil {
+		app.errorLogWrapper(link.Original, "check disk space", err)
+		return
+	}
+
+	var syncState *contextSync
+	var err error
+	if app.config.PlaylistSync {
+		syncState, err = app.newContextSync(link, downloadsDir)
+		if err != nil {
+			app.errorLogWrapper(link.Original, "load sync manifest", err)
+			return
+		}
+	}
+
 	wg := sync.WaitGroup{}
 
-	if app.requireCover(false, true) {
+	if image != nil && app.requireCover(false, true) {
 		app.downloadWorker(&wg, func() {
-			cover, err := app.downloadCover(chart.Image, downloadsDir)
+			cover, err := app.downloadCover(*image, false, true)
 			if err != nil {
 				app.errorLogWrapper(link.Original, "download chart cover", err)
 			}
-			if err := app.handleCoverFile(cover); err != nil {
+			if err := app.handleCoverFile(cover, downloadsDir); err != nil {
 				app.errorLogWrapper(link.Original, "handle cover file", err)
 				return
 			}
 		})
 	}
 
-	err = ForPaginated[beatport.Track](link.ID, "", inst.GetChartTracks, func(track beatport.Track, i int) error {
+	err = ForPaginated[beatport.Track](link.ID, "", fetchPage, func(track beatport.Track, i int) error {
+		if syncState != nil {
+			syncState.list(track.ID)
+		}
 		app.downloadWorker(&wg, func() {
-			trackStoreUrl := track.StoreUrl()
+			track.Position = i + 1
+			track.PositionTotal = trackCount
+			app.handleContextTrack(inst, &track, downloadsDir, syncState)
+		})
+		return nil
+	})
 
-			release, err := inst.GetRelease(track.Release.ID)
-			if err != nil {
-				app.errorLogWrapper(trackStoreUrl, "fetch track release", err)
-				return
-			}
-			track.Release = *release
+	if err != nil {
+		app.errorLogWrapper(link.Original, "handle tracks", err)
+		return
+	}
 
-			trackDownloadsDir := downloadsDir
-			trackFull, err := inst.GetTrack(track.ID)
-			if err != nil {
-				app.errorLogWrapper(trackStoreUrl, "fetch full track", err)
-				return
-			}
-			track.Number = trackFull.Number
-			if app.config.SortByContext && app.config.ForceReleaseDirectories {
-				trackDownloadsDir, err = app.setupDownloadsDirectory(downloadsDir, release)
-				if err != nil {
-					app.errorLogWrapper(trackStoreUrl, "setup track release directory", err)
-					return
-				}
-			}
+	wg.Wait()
 
-			var cover string
-			if app.requireCover(true, app.config.ForceReleaseDirectories) {
-				cover, err = app.downloadCover(track.Release.Image, trackDownloadsDir)
-				if err != nil {
-					app.errorLogWrapper(trackStoreUrl, "download track release cover", err)
-				} else if !app.config.ForceReleaseDirectories {
-					defer os.Remove(cover)
-				}
-			}
+	if syncState != nil {
+		if err := app.finishContextSync(syncState); err != nil {
+			app.errorLogWrapper(link.Original, "finish chart sync", err)
+		}
+	}
+}
 
-			if err := app.handleTrack(inst, &track, trackDownloadsDir, cover); err != nil {
-				app.errorLogWrapper(trackStoreUrl, "handle track", err)
-				os.Remove(cover)
-				app.cleanup(trackDownloadsDir)
-				return
-			}
+// handleContextTrack downloads a track of a playlist or chart listing, with its
+// position already set, and reports whether it was handled without errors.
+func (app *application) handleContextTrack(inst *beatport.Beatport, track *beatport.Track, downloadsDir string, syncState *contextSync) bool {
+	trackStoreUrl := track.StoreUrl()
 
-			if app.config.ForceReleaseDirectories {
-				if err := app.handleCoverFile(cover); err != nil {
-					app.errorLogWrapper(trackStoreUrl, "handle track release cover file", err)
-					return
-				}
-			}
+	release, err := inst.GetRelease(track.Release.ID)
+	if err != nil {
+		app.errorLogWrapper(trackStoreUrl, "fetch track release", err)
+		syncState.fail()
+		return false
+	}
+	track.Release = *release
 
-			app.cleanup(trackDownloadsDir)
-		})
-		return nil
-	})
+	trackDownloadsDir := downloadsDir
+	trackFull, err := inst.GetTrack(track.ID)
+	if err != nil {
+		app.errorLogWrapper(trackStoreUrl, "fetch full track", err)
+		syncState.fail()
+		return false
+	}
+	track.Number = trackFull.Number
+	if !app.filter.Match(track) {
+		return true
+	}
+	if app.config.SortByContext && app.config.ForceReleaseDirectories {
+		trackDownloadsDir, err = app.setupDownloadsDirectory(downloadsDir, release)
+		if err != nil {
+			app.errorLogWrapper(trackStoreUrl, "setup track release directory", err)
+			syncState.fail()
+			return false
+		}
+	}
+
+	if syncState != nil && app.syncTrack(syncState, track) {
+		return true
+	}
+
+	var cover *coverArt
+	if app.requireCover(true, app.config.ForceReleaseDirectories) {
+		cover, err = app.downloadCover(track.Release.Image, true, app.config.ForceReleaseDirectories)
+		if err != nil {
+			app.errorLogWrapper(trackStoreUrl, "download track release cover", err)
+		} else if !app.config.ForceReleaseDirectories {
+			defer cover.remove()
+		}
+	}
 
+	location, err := app.handleTrack(inst, track, trackDownloadsDir, cover.embedPath())
 	if err != nil {
-		app.errorLogWrapper(link.Original, "handle playlist items", err)
-		return
+		app.errorLogWrapper(trackStoreUrl, "handle track", err)
+		syncState.fail()
+		cover.remove()
+		app.cleanup(trackDownloadsDir)
+		return false
+	}
+	if syncState != nil && location != "" {
+		syncState.record(track.ID, track.Position, location)
 	}
 
-	wg.Wait()
+	if app.config.ForceReleaseDirectories {
+		if err := app.handleCoverFile(cover, trackDownloadsDir); err != nil {
+			app.errorLogWrapper(trackStoreUrl, "handle track release cover file", err)
+			return false
+		}
+	}
+
+	app.cleanup(trackDownloadsDir)
+	return true
 }
 
 func (app *application) handleLabelLink(inst *beatport.Beatport, link *beatport.Link) {
@@ -742,57 +1110,17 @@ func (app *application) handleLabelLink(inst *beatport.Beatport, link *beatport.
 		return
 	}
 
-	err = ForPaginated[beatport.Release](link.ID, link.Params, inst.GetLabelReleases, func(release beatport.Release, i int) error {
-		app.globalWorker(func() {
-			releaseStoreUrl := release.StoreUrl()
-			releaseDir, err := app.setupDownloadsDirectory(downloadsDir, &release)
-			if err != nil {
-				app.errorLogWrapper(releaseStoreUrl, "setup release downloads directory", err)
-				return
-			}
-
-			var cover string
-			if app.requireCover(true, true) {
-				app.semAcquire(app.downloadSem)
-				cover, err = app.downloadCover(release.Image, releaseDir)
-				if err != nil {
-					app.errorLogWrapper(releaseStoreUrl, "download release cover", err)
-				}
-				app.semRelease(app.downloadSem)
-			}
-
-			wg := sync.WaitGroup{}
-			err = ForPaginated[beatport.Track](release.ID, "", inst.GetReleaseTracks, func(track beatport.Track, i int) error {
-				app.downloadWorker(&wg, func() {
-					trackStoreUrl := track.StoreUrl()
-					t, err := inst.GetTrack(track.ID)
-					if err != nil {
-						app.errorLogWrapper(trackStoreUrl, "fetch full track", err)
-						return
-					}
-					t.Release = release
-
-					if err := app.handleTrack(inst, t, releaseDir, cover); err != nil {
-						app.errorLogWrapper(trackStoreUrl, "handle track", err)
-						return
-					}
-				})
-				return nil
-			})
-			if err != nil {
-				app.errorLogWrapper(releaseStoreUrl, "handle release tracks", err)
-				os.Remove(cover)
-				app.cleanup(releaseDir)
-				return
-			}
-			wg.Wait()
-
-			app.cleanup(releaseDir)
+	if err := app.saveContextImage(label.Image, downloadsDir, "logo"); err != nil {
+		app.errorLogWrapper(link.Original, "save label image", err)
+	}
 
-			if err := app.handleCoverFile(cover); err != nil {
-				app.errorLogWrapper(releaseStoreUrl, "handle cover file", err)
-				return
-			}
+	params := joinParams(link.Params, app.filter.ReleaseParams())
+	err = ForPaginated[beatport.Release](link.ID, params, inst.GetLabelReleases, func(release beatport.Release, i int) error {
+		if !app.filter.MatchRelease(&release) {
+			return nil
+		}
+		app.globalWorker(func() {
+			app.handleContextRelease(inst, release, downloadsDir)
 		})
 		return nil
 	})
@@ -803,63 +1131,91 @@ func (app *application) handleLabelLink(inst *beatport.Beatport, link *beatport.
 	}
 }
 
-func (app *application) handleArtistLink(inst *beatport.Beatport, link *beatport.Link) {
-	artist, err := inst.GetArtist(link.ID)
+// handleContextRelease downloads a release of a label or feed listing into its own
+// directory inside downloadsDir, and reports whether all its tracks were handled
+// without errors.
+func (app *application) handleContextRelease(inst *beatport.Beatport, release beatport.Release, downloadsDir string) bool {
+	releaseStoreUrl := release.StoreUrl()
+	releaseDir, err := app.setupDownloadsDirectory(downloadsDir, &release)
 	if err != nil {
-		app.errorLogWrapper(link.Original, "fetch artist", err)
-		return
+		app.errorLogWrapper(releaseStoreUrl, "setup release downloads directory", err)
+		return false
 	}
 
-	downloadsDir, err := app.setupDownloadsDirectory(app.config.DownloadsDirectory, artist)
-	if err != nil {
-		app.errorLogWrapper(link.Original, "setup downloads directory", err)
-		return
+	var cover *coverArt
+	if app.requireCover(true, true) {
+		app.semAcquire(app.downloadSem)
+		cover, err = app.downloadCover(release.Image, true, true)
+		if err != nil {
+			app.errorLogWrapper(releaseStoreUrl, "download release cover", err)
+		}
+		app.semRelease(app.downloadSem)
 	}
 
+	var failed atomic.Bool
 	wg := sync.WaitGroup{}
-	err = ForPaginated[beatport.Track](link.ID, link.Params, inst.GetArtistTracks, func(track beatport.Track, i int) error {
+	err = ForPaginated[beatport.Track](release.ID, "", inst.GetReleaseTracks, func(track beatport.Track, i int) error {
 		app.downloadWorker(&wg, func() {
 			trackStoreUrl := track.StoreUrl()
 			t, err := inst.GetTrack(track.ID)
 			if err != nil {
 				app.errorLogWrapper(trackStoreUrl, "fetch full track", err)
+				failed.Store(true)
 				return
 			}
-
-			release, err := inst.GetRelease(track.Release.ID)
-			if err != nil {
-				app.errorLogWrapper(trackStoreUrl, "fetch track release", err)
+			t.Release = release
+			if !app.filter.Match(t) {
 				return
 			}
-			t.Release = *release
 
-			releaseDir, err := app.setupDownloadsDirectory(downloadsDir, release)
-			if err != nil {
-				app.errorLogWrapper(trackStoreUrl, "setup track release downloads directory", err)
+			if _, err := app.handleTrack(inst, t, releaseDir, cover.embedPath()); err != nil {
+				app.errorLogWrapper(trackStoreUrl, "handle track", err)
+				failed.Store(true)
 				return
 			}
+		})
+		return nil
+	})
+	if err != nil {
+		app.errorLogWrapper(releaseStoreUrl, "handle release tracks", err)
+		cover.remove()
+		app.cleanup(releaseDir)
+		return false
+	}
+	wg.Wait()
 
-			var cover string
-			if app.requireCover(true, true) {
-				cover, err = app.downloadCover(release.Image, releaseDir)
-				if err != nil {
-					app.errorLogWrapper(trackStoreUrl, "download track release cover", err)
-				}
-			}
+	if err := app.handleCoverFile(cover, releaseDir); err != nil {
+		app.errorLogWrapper(releaseStoreUrl, "handle cover file", err)
+		failed.Store(true)
+	}
 
-			if err := app.handleTrack(inst, t, releaseDir, cover); err != nil {
-				app.errorLogWrapper(trackStoreUrl, "handle track", err)
-				os.Remove(cover)
-				app.cleanup(releaseDir)
-				return
-			}
+	app.cleanup(releaseDir)
+	// Workers skipped after a cancel leave no error behind
+	return !failed.Load() && app.ctx.Err() == nil
+}
 
-			if err := app.handleCoverFile(cover); err != nil {
-				app.errorLogWrapper(trackStoreUrl, "handle cover file", err)
-				return
-			}
+func (app *application) handleArtistLink(inst *beatport.Beatport, link *beatport.Link) {
+	artist, err := inst.GetArtist(link.ID)
+	if err != nil {
+		app.errorLogWrapper(link.Original, "fetch artist", err)
+		return
+	}
 
-			app.cleanup(releaseDir)
+	downloadsDir, err := app.setupDownloadsDirectory(app.config.DownloadsDirectory, artist)
+	if err != nil {
+		app.errorLogWrapper(link.Original, "setup downloads directory", err)
+		return
+	}
+
+	if err := app.saveContextImage(artist.Image, downloadsDir, "artist"); err != nil {
+		app.errorLogWrapper(link.Original, "save artist image", err)
+	}
+
+	wg := sync.WaitGroup{}
+	params := joinParams(link.Params, app.filter.TrackParams())
+	err = ForPaginated[beatport.Track](link.ID, params, inst.GetArtistTracks, func(track beatport.Track, i int) error {
+		app.downloadWorker(&wg, func() {
+			app.handleArtistTrack(inst, track, downloadsDir)
 		})
 		return nil
 	})
@@ -870,3 +1226,54 @@ func (app *application) handleArtistLink(inst *beatport.Beatport, link *beatport
 
 	wg.Wait()
 }
+
+// handleArtistTrack downloads a track of an artist listing into the directory
+// of its release inside downloadsDir, and reports whether it was handled
+// without errors.
+func (app *application) handleArtistTrack(inst *beatport.Beatport, track beatport.Track, downloadsDir string) bool {
+	trackStoreUrl := track.StoreUrl()
+	t, err := inst.GetTrack(track.ID)
+	if err != nil {
+		app.errorLogWrapper(trackStoreUrl, "fetch full track", err)
+		return false
+	}
+
+	release, err := inst.GetRelease(track.Release.ID)
+	if err != nil {
+		app.errorLogWrapper(trackStoreUrl, "fetch track release", err)
+		return false
+	}
+	t.Release = *release
+	if !app.filter.Match(t) {
+		return true
+	}
+
+	releaseDir, err := app.setupDownloadsDirectory(downloadsDir, release)
+	if err != nil {
+		app.errorLogWrapper(trackStoreUrl, "setup track release downloads directory", err)
+		return false
+	}
+
+	var cover *coverArt
+	if app.requireCover(true, true) {
+		cover, err = app.downloadCover(release.Image, true, true)
+		if err != nil {
+			app.errorLogWrapper(trackStoreUrl, "download track release cover", err)
+		}
+	}
+
+	if _, err := app.handleTrack(inst, t, releaseDir, cover.embedPath()); err != nil {
+		app.errorLogWrapper(trackStoreUrl, "handle track", err)
+		cover.remove()
+		app.cleanup(releaseDir)
+		return false
+	}
+
+	if err := app.handleCoverFile(cover, releaseDir); err != nil {
+		app.errorLogWrapper(trackStoreUrl, "handle cover file", err)
+		return false
+	}
+
+	app.cleanup(releaseDir)
+	return true
+}
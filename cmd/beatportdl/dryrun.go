@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+// plannedQuality returns the file extension, display quality and approximate
+// bitrate in kbps of the requested quality, used when nothing is downloaded.
+func plannedQuality(quality string) (string, string, int) {
+	switch quality {
+	case "medium-hls":
+		return ".m4a", "AAC 128kbps - HLS", 128
+	case "medium":
+		return ".m4a", "AAC 128kbps", 128
+	case "high":
+		return ".m4a", "AAC 256kbps", 256
+	default:
+		return ".flac", "FLAC", 900
+	}
+}
+
+func estimateSize(track *beatport.Track, quality string) int64 {
+	_, _, kbps := plannedQuality(quality)
+	return int64(track.LengthMs) * int64(kbps) / 8
+}
+
+// planTrack records and prints what would happen to the track in a dry run.
+func (app *application) planTrack(inst *beatport.Beatport, track *beatport.Track, status resultStatus, quality, location string) {
+	item := app.trackResult(inst, track, status, quality, location)
+
+	switch status {
+	case resultDownloaded:
+		item.Bytes = estimateSize(track, app.config.Quality)
+		app.infoLogWrapper(item.URL, fmt.Sprintf("would download %s [%s, ~%.1f MB]", location, quality, float64(item.Bytes)/1e6))
+	case resultUpdated:
+		app.infoLogWrapper(item.URL, fmt.Sprintf("would update the tags of %s", location))
+	case resultLinked:
+		app.infoLogWrapper(item.URL, fmt.Sprintf("would link %s to the stored download", location))
+	case resultSkipped:
+		app.infoLogWrapper(item.URL, "would skip, the file already exists")
+	}
+	app.results.add(item)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/storage"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	s := &state{logWriter: io.Discard, activeFiles: make(map[string]struct{})}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{
+		config: &config.AppConfig{
+			DryRun:             true,
+			Quality:            "lossless",
+			DownloadsDirectory: dir,
+			TrackFileTemplate:  "{name} ({mix_name})",
+			TrackExists:        "skip",
+		},
+		state:   s,
+		storage: storage.NewLocal(dir),
+	}
+	inst := beatport.New(beatport.StoreBeatport, "", nil)
+	newTrack := func(id int64, name string) *beatport.Track {
+		return &beatport.Track{ID: id, Name: beatport.SanitizedString(name), MixName: "Original Mix", LengthMs: 240000}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Existing (Original Mix).flac"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	releaseDir := filepath.Join(dir, "Release")
+	for _, track := range []*beatport.Track{newTrack(1, "New"), newTrack(2, "Existing")} {
+		directory := dir
+		if track.ID == 1 {
+			directory = releaseDir
+		}
+		if _, err := app.handleTrack(inst, track, directory, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items := app.results.take()
+	if len(items) != 2 {
+		t.Fatalf("got %d results, expected 2", len(items))
+	}
+	if items[0].Status != resultDownloaded || items[0].Path != filepath.Join(releaseDir, "New (Original Mix).flac") || items[0].Bytes != 27000000 {
+		t.Errorf("planned download = %+v", items[0])
+	}
+	if items[1].Status != resultSkipped {
+		t.Errorf("existing track status = %s, expected skipped", items[1].Status)
+	}
+	if _, err := os.Stat(releaseDir); !os.IsNotExist(err) {
+		t.Error("a dry run should not create directories or files")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+type filterFlags struct {
+	from           string
+	to             string
+	bpm            string
+	keys           string
+	compatibleKeys bool
+	genres         string
+	includeMix     string
+	excludeMix     string
+	releaseTypes   string
+	length         string
+	exclusiveOnly  bool
+}
+
+// register binds the filter flags to f, keeping the values that were already
+// parsed so that the flags can be registered on more than one flag set.
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.from, "from", f.from, "Only download releases published on or after this date (YYYY-MM-DD)")
+	fs.StringVar(&f.to, "to", f.to, "Only download releases published on or before this date (YYYY-MM-DD)")
+	fs.StringVar(&f.bpm, "bpm", f.bpm, "BPM range filter (e.g. 120-128)")
+	fs.StringVar(&f.keys, "key", f.keys, "Comma separated key filter (e.g. 8A,9A or Am,Em)")
+	fs.BoolVar(&f.compatibleKeys, "compatible-keys", f.compatibleKeys, "Include harmonically compatible keys in the key filter")
+	fs.StringVar(&f.genres, "genre", f.genres, "Comma separated genre or subgenre filter")
+	fs.StringVar(&f.includeMix, "include-mix", f.includeMix, "Comma separated mix name patterns to include")
+	fs.StringVar(&f.excludeMix, "exclude-mix", f.excludeMix, "Comma separated mix name patterns to exclude (e.g. \"Radio Edit,Extended\")")
+	fs.StringVar(&f.releaseTypes, "release-type", f.releaseTypes, "Comma separated release type filter (e.g. EP,Album)")
+	fs.StringVar(&f.length, "length", f.length, "Track length range filter (e.g. 4m-9m)")
+	fs.BoolVar(&f.exclusiveOnly, "exclusive", f.exclusiveOnly, "Only download Beatport exclusives")
+}
+
+// apply overrides the config filters with the values provided on the command line.
+func (f *filterFlags) apply(c *config.Filters) error {
+	if f.from != "" {
+		c.ReleaseDateFrom = f.from
+	}
+	if f.to != "" {
+		c.ReleaseDateTo = f.to
+	}
+	if f.bpm != "" {
+		var err error
+		if c.BPMMin, c.BPMMax, err = parseBPMRange(f.bpm); err != nil {
+			return err
+		}
+	}
+	if f.keys != "" {
+		c.Keys = splitList(f.keys)
+	}
+	if f.compatibleKeys {
+		c.CompatibleKeys = true
+	}
+	if f.genres != "" {
+		c.Genres = splitList(f.genres)
+	}
+	if f.includeMix != "" {
+		c.IncludeMixNames = splitList(f.includeMix)
+	}
+	if f.excludeMix != "" {
+		c.ExcludeMixNames = splitList(f.excludeMix)
+	}
+	if f.releaseTypes != "" {
+		c.ReleaseTypes = splitList(f.releaseTypes)
+	}
+	if f.length != "" {
+		lengthMin, lengthMax, _ := strings.Cut(f.length, "-")
+		c.LengthMin = strings.TrimSpace(lengthMin)
+		c.LengthMax = strings.TrimSpace(lengthMax)
+	}
+	if f.exclusiveOnly {
+		c.ExclusiveOnly = true
+	}
+	return nil
+}
+
+func newTrackFilter(c config.Filters) (*beatport.TrackFilter, error) {
+	filter := &beatport.TrackFilter{
+		DateFrom:      c.ReleaseDateFrom,
+		DateTo:        c.ReleaseDateTo,
+		BPMMin:        c.BPMMin,
+		BPMMax:        c.BPMMax,
+		Genres:        c.Genres,
+		ReleaseTypes:  c.ReleaseTypes,
+		ExclusiveOnly: c.ExclusiveOnly,
+	}
+
+	for _, key := range c.Keys {
+		code, err := beatport.ParseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		codes := []string{code}
+		if c.CompatibleKeys {
+			codes = beatport.CompatibleKeys(code)
+		}
+		for _, code := range codes {
+			if !slices.Contains(filter.Keys, code) {
+				filter.Keys = append(filter.Keys, code)
+			}
+		}
+	}
+
+	for _, pattern := range c.IncludeMixNames {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		filter.IncludeMixNames = append(filter.IncludeMixNames, re)
+	}
+	for _, pattern := range c.ExcludeMixNames {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		filter.ExcludeMixNames = append(filter.ExcludeMixNames, re)
+	}
+
+	if c.LengthMin != "" {
+		length, _ := time.ParseDuration(c.LengthMin)
+		filter.LengthMin = beatport.Duration(length.Milliseconds())
+	}
+	if c.LengthMax != "" {
+		length, _ := time.ParseDuration(c.LengthMax)
+		filter.LengthMax = beatport.Duration(length.Milliseconds())
+	}
+
+	return filter, nil
+}
+
+// parseBPMRange parses a single BPM value (126) or a range (120-128).
+func parseBPMRange(s string) (int, int, error) {
+	bpmMin, bpmMax, found := strings.Cut(s, "-")
+	minValue, err := strconv.Atoi(strings.TrimSpace(bpmMin))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid bpm range: %s", s)
+	}
+	if !found {
+		return minValue, minValue, nil
+	}
+	maxValue, err := strconv.Atoi(strings.TrimSpace(bpmMax))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid bpm range: %s", s)
+	}
+	return minValue, maxValue, nil
+}
+
+func joinParams(params ...string) string {
+	var nonEmpty []string
+	for _, p := range params {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, "&")
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+const (
+	hookEventTrack = "track"
+	hookEventURL   = "url"
+
+	hookEnvPrefix = "BEATPORTDL_HOOK_"
+)
+
+// hookPayload is passed to the hook commands as JSON on stdin, and to the
+// webhooks when they have no body template.
+type hookPayload struct {
+	Event    string          `json:"event"`
+	URL      string          `json:"url"`
+	Store    beatport.Store  `json:"store,omitempty"`
+	Status   resultStatus    `json:"status,omitempty"`
+	Path     string          `json:"path,omitempty"`
+	Quality  string          `json:"quality,omitempty"`
+	Duration string          `json:"duration,omitempty"`
+	Track    *beatport.Track `json:"track,omitempty"`
+}
+
+// values returns the flat payload values used in the body templates and the
+// environment variables of the hook commands.
+func (p *hookPayload) values() map[string]string {
+	values := map[string]string{
+		"event":    p.Event,
+		"url":      p.URL,
+		"store":    string(p.Store),
+		"status":   string(p.Status),
+		"path":     p.Path,
+		"quality":  p.Quality,
+		"duration": p.Duration,
+	}
+	if t := p.Track; t != nil {
+		values["track_id"] = strconv.FormatInt(t.ID, 10)
+		values["name"] = t.Name.String()
+		values["mix_name"] = t.MixName.String()
+		values["artists"] = t.Artists.Display(0, "")
+		values["remixers"] = t.Remixers.Display(0, "")
+		values["bpm"] = strconv.Itoa(t.BPM)
+		values["key"] = t.Key.Display("camelot")
+		values["genre"] = t.Genre.Name
+		values["isrc"] = t.ISRC
+		values["release_id"] = strconv.FormatInt(t.Release.ID, 10)
+		values["release"] = t.Release.Name.String()
+		values["label"] = t.Release.Label.Name
+		values["catalog_number"] = t.Release.CatalogNumber.String()
+		values["release_date"] = t.Release.Date
+	}
+	return values
+}
+
+func (app *application) runTrackHooks(inst *beatport.Beatport, track *beatport.Track, status resultStatus, quality, location string) {
+	if len(app.config.Hooks.Track) == 0 || app.config.DryRun {
+		return
+	}
+	app.runHooks(app.config.Hooks.Track, &hookPayload{
+		Event:   hookEventTrack,
+		URL:     track.StoreUrl(),
+		Store:   inst.Store(),
+		Status:  status,
+		Path:    location,
+		Quality: quality,
+		Track:   track,
+	})
+}
+
+func (app *application) runURLHooks(link *beatport.Link, duration time.Duration) {
+	if len(app.config.Hooks.URL) == 0 || app.config.DryRun {
+		return
+	}
+	app.runHooks(app.config.Hooks.URL, &hookPayload{
+		Event:    hookEventURL,
+		URL:      link.Original,
+		Store:    link.Store,
+		Duration: duration.Round(time.Millisecond).String(),
+	})
+}
+
+// runHooks runs the hooks one after another. Failures are only logged, they
+// never fail the download.
+func (app *application) runHooks(hooks []config.Hook, payload *hookPayload) {
+	timeout, _ := time.ParseDuration(app.config.Hooks.Timeout)
+	for _, hook := range hooks {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var err error
+		if hook.Webhook != "" {
+			err = callWebhook(ctx, hook, payload)
+		} else {
+			err = runHookCommand(ctx, hook, payload)
+		}
+		cancel()
+		if err != nil {
+			name := hook.Webhook
+			if name == "" {
+				name = hook.Command[0]
+			}
+			app.logger.Warn("hook failed", "url", payload.URL, "step", "run "+payload.Event+" hook "+name, "error", err)
+		}
+	}
+}
+
+func runHookCommand(ctx context.Context, hook config.Hook, payload *hookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = os.Environ()
+	for key, value := range payload.values() {
+		cmd.Env = append(cmd.Env, hookEnvPrefix+strings.ToUpper(key)+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func callWebhook(ctx context.Context, hook config.Hook, payload *hookPayload) error {
+	var body []byte
+	if hook.Body != "" {
+		values := payload.values()
+		for key, value := range values {
+			values[key] = jsonEscape(value)
+		}
+		body = []byte(beatport.ParseTemplate(hook.Body, values))
+	} else {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bad status: %s", resp.Status)
+	}
+	return nil
+}
+
+// jsonEscape escapes s to be placed inside a JSON string in a body template.
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+func TestHooks(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	s := &state{logWriter: io.Discard}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{
+		config: &config.AppConfig{
+			Hooks: config.Hooks{
+				Timeout: "5s",
+				Track: []config.Hook{
+					{Webhook: server.URL, Body: `{"content": "{artists} - {name} ({mix_name})"}`},
+					{Command: []string{"sh", "-c", `cat > "$BEATPORTDL_HOOK_PATH"`}},
+					{Command: []string{"false"}},
+				},
+			},
+		},
+		state: s,
+	}
+
+	track := &beatport.Track{
+		ID:      1,
+		Name:    `Say "Hello"`,
+		MixName: "Original Mix",
+		Artists: beatport.Artists{{Name: "Artist"}},
+	}
+	app.runTrackHooks(beatport.New(beatport.StoreBeatport, "", nil), track, resultDownloaded, "FLAC", marker)
+
+	if received["content"] != `Artist - Say "Hello" (Original Mix)` {
+		t.Errorf("webhook content = %q", received["content"])
+	}
+	var payload hookPayload
+	data, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("hook command did not run: %v", err)
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Track.ID != 1 || payload.Path != marker {
+		t.Errorf("hook command payload = %s", data)
+	}
+	if app.failed.Load() != 0 {
+		t.Error("a failed hook should not fail the download")
+	}
+}
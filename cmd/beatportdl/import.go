@@ -0,0 +1,390 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/tracklist"
+)
+
+const (
+	defaultImportConfidence = 0.8
+	// importMinCandidate is the score below which search results are not
+	// offered as candidates at all.
+	importMinCandidate = 0.5
+	// importAmbiguityMargin is how close the second best candidate has to be
+	// for a match to need confirmation.
+	importAmbiguityMargin = 0.05
+	importCandidates      = 5
+)
+
+type importStatus string
+
+const (
+	importMatched   importStatus = "matched"
+	importAmbiguous importStatus = "ambiguous"
+	importMissing   importStatus = "missing"
+	importFailed    importStatus = "failed"
+)
+
+type importCandidate struct {
+	track      *beatport.Track
+	confidence float64
+}
+
+type importResult struct {
+	entry      tracklist.Entry
+	status     importStatus
+	matchedBy  string
+	url        string
+	candidates []importCandidate
+	err        error
+}
+
+type importOptions struct {
+	store         beatport.Store
+	minConfidence float64
+	// accept queues the best candidate of ambiguous entries without asking
+	accept bool
+	report io.Writer
+}
+
+func (app *application) importCommand(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	storeFlag := fs.String("store", string(beatport.StoreBeatport), "Store to match the tracks against (beatport, beatsource)")
+	confidenceFlag := fs.Float64("min-confidence", defaultImportConfidence, "Confidence (0-1) above which search matches are queued without confirmation")
+	yesFlag := fs.Bool("yes", false, "Queue the best candidate of ambiguous matches without asking")
+	urlsFlag := fs.Bool("urls", false, "Print the matched URLs instead of downloading them")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: beatportdl import [flags] <file>...")
+		os.Exit(exitUsage)
+	}
+
+	options := importOptions{
+		store:         beatport.Store(*storeFlag),
+		minConfidence: *confidenceFlag,
+		accept:        *yesFlag,
+		report:        os.Stdout,
+	}
+	if *urlsFlag {
+		// Keeps stdout for the URLs
+		options.report = os.Stderr
+	}
+	for _, path := range fs.Args() {
+		if err := app.importTracklist(path, options); err != nil {
+			app.FatalError("import", err)
+		}
+	}
+
+	if *urlsFlag {
+		for _, url := range app.urls {
+			fmt.Println(url)
+		}
+		return
+	}
+	if len(app.urls) == 0 {
+		return
+	}
+	app.downloadUrls()
+}
+
+// importTracklist resolves the entries of a track list and queues the
+// matched tracks. Links are queued as they are.
+func (app *application) importTracklist(path string, options importOptions) error {
+	entries, err := tracklist.Parse(path)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	lookups := 0
+	for _, entry := range entries {
+		if entry.URL == "" {
+			lookups++
+		}
+	}
+	if lookups == 0 {
+		for _, entry := range entries {
+			app.urls = append(app.urls, entry.URL)
+		}
+		return nil
+	}
+
+	inst, err := app.storeInstance(options.store)
+	if err != nil {
+		return err
+	}
+
+	results := make([]importResult, len(entries))
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, app.config.MaxGlobalWorkers)
+	for i, entry := range entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = app.resolveEntry(inst, entry, options.minConfidence)
+		}()
+	}
+	wg.Wait()
+
+	counts := make(map[importStatus]int)
+	for i := range results {
+		result := &results[i]
+		if result.status == importAmbiguous {
+			app.confirmImport(result, options)
+		}
+		counts[result.status]++
+
+		switch result.status {
+		case importMatched:
+			app.urls = append(app.urls, result.url)
+			if result.matchedBy == "url" {
+				continue
+			}
+			fmt.Fprintf(options.report, "[%s %.2f] %s\n  %s (matched by %s)\n", result.status, result.candidates[0].confidence, result.entry, result.url, result.matchedBy)
+		case importFailed:
+			app.LogError("import", result.err, "entry", result.entry.String())
+		default:
+			fmt.Fprintf(options.report, "[%s] %s\n", result.status, result.entry)
+		}
+	}
+	fmt.Fprintf(
+		options.report,
+		"Imported %d entries from %s: %d matched, %d skipped, %d missing, %d failed\n",
+		len(results), path, counts[importMatched], counts[importAmbiguous], counts[importMissing], counts[importFailed],
+	)
+	return nil
+}
+
+// resolveEntry finds the track of an entry by ISRC, falling back to a
+// search scored on the artists, title, mix name and duration.
+func (app *application) resolveEntry(inst *beatport.Beatport, entry tracklist.Entry, minConfidence float64) importResult {
+	result := importResult{entry: entry}
+	if entry.URL != "" {
+		result.status = importMatched
+		result.matchedBy = "url"
+		result.url = entry.URL
+		return result
+	}
+
+	if entry.ISRC != "" {
+		tracks, err := inst.GetTracksByISRC(entry.ISRC)
+		if err != nil {
+			result.status = importFailed
+			result.err = fmt.Errorf("fetch tracks by isrc: %w", err)
+			return result
+		}
+		if candidates := rankTracks(entry, tracks, 0); len(candidates) > 0 {
+			// The ISRC identifies the recording, the score only picks the release
+			candidates[0].confidence = 1
+			result.status = importMatched
+			result.matchedBy = "isrc"
+			result.url = candidates[0].track.StoreUrl()
+			result.candidates = candidates[:1]
+			return result
+		}
+	}
+
+	if entry.Title == "" {
+		result.status = importMissing
+		return result
+	}
+	query := strings.TrimSpace(strings.Join([]string{entry.Artists, entry.Title, entry.MixName}, " "))
+	results, err := inst.Search(query, beatport.SearchOptions{
+		Types: []beatport.SearchType{beatport.SearchTracks},
+	})
+	if err != nil {
+		result.status = importFailed
+		result.err = fmt.Errorf("search: %w", err)
+		return result
+	}
+
+	result.candidates = rankTracks(entry, results.Tracks, importMinCandidate)
+	switch {
+	case len(result.candidates) == 0:
+		result.status = importMissing
+	case result.candidates[0].confidence < minConfidence,
+		len(result.candidates) > 1 && result.candidates[0].confidence-result.candidates[1].confidence < importAmbiguityMargin:
+		result.status = importAmbiguous
+		result.matchedBy = "search"
+	default:
+		result.status = importMatched
+		result.matchedBy = "search"
+		result.url = result.candidates[0].track.StoreUrl()
+	}
+	return result
+}
+
+// confirmImport asks which candidate of an ambiguous entry to queue. Without
+// a terminal the entry is skipped, unless accept is set.
+func (app *application) confirmImport(result *importResult, options importOptions) {
+	if options.accept {
+		result.status = importMatched
+		result.url = result.candidates[0].track.StoreUrl()
+		return
+	}
+	if !interactive || !stdinIsTerminal() {
+		return
+	}
+
+	fmt.Fprintf(options.report, "Ambiguous match for %s:\n", result.entry)
+	candidates := result.candidates
+	if len(candidates) > importCandidates {
+		candidates = candidates[:importCandidates]
+	}
+	for i, candidate := range candidates {
+		track := candidate.track
+		fmt.Fprintf(
+			options.report,
+			"%2d. [%.2f] %s - %s [%s] %s\n",
+			i+1,
+			candidate.confidence,
+			track.Artists.Display(app.config.ArtistsLimit, app.config.ArtistsShortForm),
+			trackTitle(track),
+			track.Length,
+			track.StoreUrl(),
+		)
+	}
+	for {
+		fmt.Fprint(options.report, "Enter the candidate number, or leave empty to skip: ")
+		input := app.promptLine()
+		if input == "" {
+			return
+		}
+		number, err := strconv.Atoi(input)
+		if err != nil || number < 1 || number > len(candidates) {
+			fmt.Fprintf(options.report, "invalid candidate number: %s\n", input)
+			continue
+		}
+		result.status = importMatched
+		result.candidates = []importCandidate{candidates[number-1]}
+		result.url = candidates[number-1].track.StoreUrl()
+		return
+	}
+}
+
+// rankTracks scores the tracks against the entry and returns the ones
+// scoring at least minScore, best first.
+func rankTracks(entry tracklist.Entry, tracks []beatport.Track, minScore float64) []importCandidate {
+	var candidates []importCandidate
+	seen := make(map[int64]bool)
+	for i := range tracks {
+		track := &tracks[i]
+		if seen[track.ID] {
+			continue
+		}
+		seen[track.ID] = true
+		if score := scoreTrack(entry, track); score >= minScore {
+			candidates = append(candidates, importCandidate{track: track, confidence: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].confidence > candidates[j].confidence
+	})
+	return candidates
+}
+
+// scoreTrack rates how well a track matches an entry from 0 to 1. The title
+// weighs the most, then the artists, the mix name and the duration. Fields
+// missing from the entry are left out of the score.
+func scoreTrack(entry tracklist.Entry, track *beatport.Track) float64 {
+	var total, weights float64
+	add := func(score, weight float64) {
+		total += score * weight
+		weights += weight
+	}
+
+	title := similarity(normalizeTitle(entry.Title), normalizeTitle(track.Name.String()))
+	mix := normalizeTitle(track.MixName.String())
+	var mixScore float64
+	switch {
+	case entry.MixName != "":
+		mixScore = similarity(normalizeTitle(entry.MixName), mix)
+	case mix == "originalmix" || mix == "extendedmix":
+		mixScore = 1
+	default:
+		mixScore = 0.5
+	}
+	if entry.MixName == "" {
+		// The mix name may still be part of the title
+		if full := similarity(normalizeTitle(entry.Title), normalizeTitle(trackTitle(track))); full > title {
+			title, mixScore = full, full
+		}
+	}
+	add(title, 0.45)
+	add(mixScore, 0.15)
+
+	if entry.Artists != "" {
+		add(artistsScore(entry.Artists, track), 0.3)
+	}
+
+	if entry.Duration > 0 && track.LengthMs > 0 {
+		length := time.Duration(track.LengthMs) * time.Millisecond
+		diff := (entry.Duration - length).Abs()
+		// Full marks within 3 seconds, nothing from 30 seconds off
+		score := 1 - float64(diff-3*time.Second)/float64(27*time.Second)
+		add(min(max(score, 0), 1), 0.1)
+	}
+
+	score := total / weights
+	if title < 0.7 {
+		// Matching artists and durations do not make up for another title
+		score *= title
+	}
+	return score
+}
+
+// artistsScore is the share of the track artists named in the entry, or
+// the string similarity of both lists when that is higher.
+func artistsScore(artists string, track *beatport.Track) float64 {
+	if len(track.Artists) == 0 {
+		return 0
+	}
+	normalized := normalizeTitle(artists)
+	var found, names []string
+	for _, artist := range track.Artists {
+		name := normalizeTitle(artist.Name)
+		names = append(names, name)
+		if name != "" && strings.Contains(normalized, name) {
+			found = append(found, name)
+		}
+	}
+	share := float64(len(found)) / float64(len(track.Artists))
+	return max(share, similarity(normalized, strings.Join(names, "")))
+}
+
+// similarity is 1 minus the Levenshtein distance of a and b relative to the
+// longer string.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			prev, row[j] = row[j], min(row[j]+1, row[j-1]+1, prev+cost)
+		}
+	}
+	return 1 - float64(row[len(rb)])/float64(max(len(ra), len(rb)))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/tracklist"
+)
+
+func TestRankTracks(t *testing.T) {
+	tracks := []beatport.Track{
+		{ID: 1, Name: "Losing It", MixName: "Radio Edit", Artists: beatport.Artists{{Name: "FISHER"}}, LengthMs: 180000},
+		{ID: 2, Name: "Losing It", MixName: "Original Mix", Artists: beatport.Artists{{Name: "Someone Else"}}, LengthMs: 332000},
+		{ID: 3, Name: "Losing It", MixName: "Original Mix", Artists: beatport.Artists{{Name: "FISHER"}}, LengthMs: 332000},
+		{ID: 4, Name: "Something Else", MixName: "Original Mix", Artists: beatport.Artists{{Name: "FISHER"}}, LengthMs: 332000},
+	}
+
+	tests := []struct {
+		entry tracklist.Entry
+		want  int64
+		exact bool
+	}{
+		{tracklist.Entry{Artists: "Fisher", Title: "Losing It", MixName: "Original Mix", Duration: 332 * time.Second}, 3, true},
+		{tracklist.Entry{Artists: "FISHER", Title: "Losing It (Radio Edit)"}, 1, true},
+		{tracklist.Entry{Artists: "Fisher", Title: "Losin It"}, 3, false},
+		{tracklist.Entry{Title: "Losing It", Duration: 180 * time.Second}, 1, false},
+	}
+	for _, tt := range tests {
+		candidates := rankTracks(tt.entry, tracks, importMinCandidate)
+		if len(candidates) == 0 {
+			t.Errorf("rankTracks(%s) found no candidates", tt.entry)
+			continue
+		}
+		if got := candidates[0].track.ID; got != tt.want {
+			t.Errorf("rankTracks(%s) = %d, want %d", tt.entry, got, tt.want)
+		}
+		if tt.exact && candidates[0].confidence != 1 {
+			t.Errorf("rankTracks(%s) confidence = %.2f, want 1", tt.entry, candidates[0].confidence)
+		}
+		for _, candidate := range candidates {
+			if candidate.track.ID == 4 {
+				t.Errorf("rankTracks(%s) returned a track with another title", tt.entry)
+			}
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"losingit", "losingit", 1},
+		{"losingit", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("similarity(%q, %q) = %f, want %f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -4,18 +4,24 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"unspok3n/beatportdl/config"
 	"unspok3n/beatportdl/internal/beatport"
 )
 
-func Setup() (cfg *config.AppConfig, cachePath string, err error) {
+func Setup() (cfg *config.AppConfig, err error) {
 	configFilePath, exists, err := FindConfigFile()
 	if err != nil {
-		return nil, "", err
+		return nil, err
+	}
+
+	_, envUsername := os.LookupEnv(config.EnvName("username"))
+	if !exists && (!interactive || envUsername) {
+		// Containers and CI can be configured entirely with environment variables
+		return config.Default(), nil
 	}
 
 	if !exists {
@@ -55,27 +61,23 @@ func Setup() (cfg *config.AppConfig, cachePath string, err error) {
 		}
 
 		if err := cfg.Save(configFilePath); err != nil {
-			return nil, configFilePath, fmt.Errorf("save config: %w", err)
+			return nil, fmt.Errorf("save config: %w", err)
 		}
 	}
 
-	parsedConfig, err := config.Parse(configFilePath)
-	if err != nil {
-		return nil, configFilePath, fmt.Errorf("load config: %w", err)
-	}
-
-	cacheFilePath, exists, err := FindCacheFile()
+	parsedConfig, err := config.Load(configFilePath)
 	if err != nil {
-		return nil, configFilePath, fmt.Errorf("get executable path: %w", err)
+		return nil, fmt.Errorf("load config: %w", err)
 	}
 
-	return parsedConfig, cacheFilePath, nil
+	return parsedConfig, nil
 }
 
 func (app *application) mainPrompt() {
 	fmt.Print("Enter url or search query: ")
-	input := GetLine()
-	if strings.HasPrefix(input, "https://www.beatport.com") || strings.HasPrefix(input, "https://www.beatsource.com") {
+	input := app.promptLine()
+	_, url, _ := cutProfilePrefix(input)
+	if strings.HasPrefix(url, "https://www.beatport.com") || strings.HasPrefix(url, "https://www.beatsource.com") {
 		app.urls = append(app.urls, input)
 	} else {
 		app.search(input)
@@ -83,102 +85,83 @@ func (app *application) mainPrompt() {
 }
 
 func (app *application) search(input string) {
-	var storeTag string
-	var inst *beatport.Beatport
-	storeTag, input = extractStoreTag(input)
-	switch storeTag {
-	default:
-		inst = app.bp
-	case "beatsource":
-		inst = app.bs
+	query, store, options, err := parseSearchInput(input)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
 	}
-
-	results, err := inst.Search(input)
+	inst, err := app.storeInstance(store)
 	if err != nil {
 		app.FatalError("beatport", err)
 	}
-	trackResultsLen := len(results.Tracks)
-	releasesResultsLen := len(results.Releases)
-
-	if trackResultsLen+releasesResultsLen == 0 {
-		fmt.Println("No results found")
-		return
-	}
 
-	fmt.Println("Search results:")
-	fmt.Println("[ Tracks ]")
-	for i, track := range results.Tracks {
-		fmt.Printf(
-			"%2d. %s - %s (%s) [%s]\n", i+1,
-			track.Artists.Display(
-				app.config.ArtistsLimit,
-				app.config.ArtistsShortForm,
-			),
-			track.Name.String(),
-			track.MixName.String(),
-			track.Length,
-		)
-	}
-	fmt.Println("\n[ Releases ]")
-	indexOffset := trackResultsLen + 1
-	for i, release := range results.Releases {
-		fmt.Printf(
-			"%2d. %s - %s [%s]\n", i+indexOffset,
-			release.Artists.Display(
-				app.config.ArtistsLimit,
-				app.config.ArtistsShortForm,
-			),
-			release.Name.String(),
-			release.Label.Name,
-		)
-	}
-	fmt.Print("Enter the result number(s): ")
-	input = GetLine()
-	requestedResults := strings.Split(input, " ")
-	for _, result := range requestedResults {
-		resultInt, err := strconv.Atoi(result)
+	for {
+		results, err := inst.Search(query, options)
 		if err != nil {
-			fmt.Printf("invalid result number: %s\n", result)
-			continue
+			app.FatalError("beatport", err)
 		}
+		flat := app.flattenSearchResults(results)
 
-		if resultInt > releasesResultsLen+trackResultsLen || resultInt == 0 {
-			fmt.Printf("invalid result number: %d\n", resultInt)
-			continue
+		if len(flat) == 0 {
+			fmt.Println("No results found")
+			if options.Page <= 1 {
+				return
+			}
+		} else {
+			fmt.Printf("Search results (page %d):\n", options.Page)
+			printSearchResults(flat, false)
 		}
 
-		if resultInt >= indexOffset {
-			app.urls = append(app.urls, results.Releases[resultInt-indexOffset].URL)
-		} else {
-			app.urls = append(app.urls, results.Tracks[resultInt-1].URL)
+		fmt.Print("Enter the result number(s), n/p for next/previous page: ")
+		input = app.promptLine()
+		switch input {
+		case "n":
+			options.Page++
+			continue
+		case "p":
+			if options.Page > 1 {
+				options.Page--
+			}
+			continue
 		}
-	}
-}
 
-func extractStoreTag(query string) (store, trimmedQuery string) {
-	re := regexp.MustCompile(`@\w+`)
-	matches := re.FindAllString(query, -1)
-	if len(matches) > 0 {
-		store = strings.TrimPrefix(matches[0], "@")
-		trimmedQuery = re.ReplaceAllString(query, "")
-		trimmedQuery = strings.TrimSpace(trimmedQuery)
-	} else {
-		trimmedQuery = query
+		for _, result := range strings.Fields(input) {
+			resultInt, err := strconv.Atoi(result)
+			if err != nil {
+				fmt.Printf("invalid result number: %s\n", result)
+				continue
+			}
+			if resultInt > len(flat) || resultInt < 1 {
+				fmt.Printf("invalid result number: %d\n", resultInt)
+				continue
+			}
+			app.urls = append(app.urls, flat[resultInt-1].URL)
+		}
+		return
 	}
-	return store, trimmedQuery
 }
 
+// parseTextFile queues the links of a text file or a track list. Entries
+// that are not links are matched with the default import options.
 func (app *application) parseTextFile(path string) {
-	file, err := os.Open(path)
-	defer file.Close()
-	if err != nil {
+	options := importOptions{
+		store:         beatport.StoreBeatport,
+		minConfidence: defaultImportConfidence,
+		report:        os.Stdout,
+	}
+	if err := app.importTracklist(path, options); err != nil {
 		app.FatalError("read input text file", err)
 	}
-	scanner := bufio.NewScanner(file)
+}
+
+func (app *application) readUrls(r io.Reader) {
+	scanner := bufio.NewScanner(r)
 	scanner.Split(bufio.ScanLines)
 
 	for scanner.Scan() {
-		app.urls = append(app.urls, scanner.Text())
+		if url := strings.TrimSpace(scanner.Text()); url != "" {
+			app.urls = append(app.urls, url)
+		}
 	}
 }
 
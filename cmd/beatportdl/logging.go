@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/rotate"
+)
+
+// consoleHandler prints the records as the short "[url] step: error" lines
+// shown next to the progress bars. The other attributes are only printed in
+// the debug level.
+type consoleHandler struct {
+	state *state
+	level slog.Leveler
+	attrs []slog.Attr
+}
+
+func newConsoleHandler(s *state, level slog.Leveler) *consoleHandler {
+	return &consoleHandler{state: s, level: level}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var url, step, errMessage string
+	var extra []string
+	handleAttr := func(a slog.Attr) bool {
+		switch a.Key {
+		case "url":
+			url = a.Value.String()
+		case "step":
+			step = a.Value.String()
+		case "error":
+			errMessage = a.Value.String()
+		case "stack":
+		default:
+			extra = append(extra, fmt.Sprintf("%s=%s", a.Key, a.Value))
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		handleAttr(a)
+	}
+	r.Attrs(handleAttr)
+
+	var line strings.Builder
+	if url != "" {
+		fmt.Fprintf(&line, "[%s] ", url)
+	}
+	if step != "" {
+		line.WriteString(step)
+	} else {
+		line.WriteString(r.Message)
+	}
+	if errMessage != "" {
+		fmt.Fprintf(&line, ": %s", errMessage)
+	}
+	if h.level.Level() <= slog.LevelDebug && len(extra) > 0 {
+		fmt.Fprintf(&line, " (%s)", strings.Join(extra, " "))
+	}
+	line.WriteString("\n")
+
+	_, err := io.WriteString(h.state.logWriter, line.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &handler
+}
+
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// multiHandler sends the records to the console and to the log file.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+func parseLogLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// setupLogger creates the console logger and the log file sink. The error log
+// file of write_error_log is used when no log_file is set, with only the errors.
+func (s *state) setupLogger(cfg *config.AppConfig) error {
+	level := parseLogLevel(cfg.LogLevel)
+	options := &slog.HandlerOptions{Level: level}
+
+	var handlers multiHandler
+	switch cfg.LogFormat {
+	case "json":
+		handlers = append(handlers, slog.NewJSONHandler(writerFunc(s.writeLog), options))
+	case "text":
+		handlers = append(handlers, slog.NewTextHandler(writerFunc(s.writeLog), options))
+	default:
+		handlers = append(handlers, newConsoleHandler(s, level))
+	}
+
+	logFilePath, fileOptions := cfg.LogFile, options
+	if logFilePath == "" && cfg.WriteErrorLog {
+		var err error
+		if logFilePath, _, err = FindErrorLogFile(); err != nil {
+			return err
+		}
+		fileOptions = &slog.HandlerOptions{Level: slog.LevelError}
+	}
+	if logFilePath != "" {
+		f, err := rotate.Open(filepath.Clean(logFilePath), int64(cfg.LogMaxSize)<<20, cfg.LogMaxBackups)
+		if err != nil {
+			return err
+		}
+		s.logFile = f
+		if cfg.LogFileFormat == "text" {
+			handlers = append(handlers, slog.NewTextHandler(f, fileOptions))
+		} else {
+			handlers = append(handlers, slog.NewJSONHandler(f, fileOptions))
+		}
+	}
+
+	s.logger = slog.New(handlers)
+	return nil
+}
+
+// writeLog writes to the current log writer, which is the progress bar
+// container while downloading.
+func (s *state) writeLog(p []byte) (int, error) {
+	return s.logWriter.Write(p)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
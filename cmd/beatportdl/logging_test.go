@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var out bytes.Buffer
+	s := &state{logWriter: &out}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{state: s}
+
+	app.errorLogWrapper("https://www.beatport.com/track/name/123", "fetch track", errors.New("not found"))
+	app.LogInfo("done")
+	app.logger.Debug("hidden")
+
+	expected := "[https://www.beatport.com/track/name/123] fetch track: not found\ndone\n"
+	if out.String() != expected {
+		t.Errorf("output = %q, expected %q", out.String(), expected)
+	}
+	if app.failed.Load() != 1 {
+		t.Errorf("failed = %d, expected 1", app.failed.Load())
+	}
+}
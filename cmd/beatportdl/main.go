@@ -7,26 +7,32 @@ import (
 	"github.com/fatih/color"
 	"github.com/vbauerster/mpb/v8"
 	"io"
+	"log/slog"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"unspok3n/beatportdl/config"
 	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/storage"
+	"unspok3n/beatportdl/internal/tracklist"
 )
 
 const (
 	configFilename = "beatportdl-config.yml"
 	cacheFilename  = "beatportdl-credentials.json"
 	errorFilename  = "beatportdl-err.log"
+	watchFilename  = "beatportdl-watch.json"
 )
 
-type application struct {
-	config      *config.AppConfig
-	logFile     *os.File
+// state is shared by the applications of all profiles used in one run.
+type state struct {
+	logger      *slog.Logger
+	logFile     io.Closer
 	logWriter   io.Writer
 	ctx         context.Context
+	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	downloadSem chan struct{}
 	globalSem   chan struct{}
@@ -35,13 +41,70 @@ type application struct {
 	urls             []string
 	activeFiles      map[string]struct{}
 	activeFilesMutex sync.RWMutex
+	trackLocks       sync.Map
+
+	// diskPending are the bytes reserved by the downloads in progress
+	diskPending    atomic.Int64
+	diskMutex      sync.Mutex
+	diskFull       atomic.Bool
+	diskFullLogged atomic.Bool
+
+	baseConfig    *config.AppConfig
+	flags         *cliFlags
+	profiles      map[string]*application
+	profilesMutex sync.Mutex
+
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	results   results
+
+	// prompting is set while waiting for input, when a shutdown signal
+	// has nothing to wait for
+	prompting   atomic.Bool
+	interrupted atomic.Bool
+}
+
+type application struct {
+	*state
+
+	profile string
+	config  *config.AppConfig
 
 	bp *beatport.Beatport
 	bs *beatport.Beatport
+
+	filter  *beatport.TrackFilter
+	storage storage.Storage
 }
 
 func main() {
-	cfg, cachePath, err := Setup()
+	flags := &cliFlags{}
+	flags.register(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+	interactive = !flags.nonInteractive
+
+	command, args := "download", flag.Args()
+	if len(args) > 0 && isCommand(args[0]) {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "help":
+		usage()
+		os.Exit(exitSuccess)
+	case "config":
+		configCommand(args, flags)
+		return
+	case "download":
+		fs := flag.NewFlagSet("download", flag.ExitOnError)
+		flags.register(fs)
+		fs.Parse(args)
+		args = fs.Args()
+		interactive = !flags.nonInteractive
+	}
+
+	cfg, err := Setup()
 	if err != nil {
 		fmt.Println(err.Error())
 		Pause()
@@ -49,92 +112,154 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	app := &application{
-		config:      cfg,
-		downloadSem: make(chan struct{}, cfg.MaxDownloadWorkers),
-		globalSem:   make(chan struct{}, cfg.MaxGlobalWorkers),
-		ctx:         ctx,
-		logWriter:   os.Stdout,
+	s := &state{
+		ctx:        ctx,
+		cancel:     cancel,
+		logWriter:  os.Stdout,
+		baseConfig: cfg,
+		flags:      flags,
+		profiles:   make(map[string]*application),
+	}
+
+	app, err := s.newApplication(flags.profile)
+	if err != nil {
+		fmt.Println(err.Error())
+		Pause()
+	}
+	s.downloadSem = make(chan struct{}, app.config.MaxDownloadWorkers)
+	s.globalSem = make(chan struct{}, app.config.MaxGlobalWorkers)
+	s.profiles[flags.profile] = app
+
+	if err := s.setupLogger(app.config); err != nil {
+		fmt.Println(err.Error())
+		Pause()
+	}
+	if s.logFile != nil {
+		defer s.logFile.Close()
 	}
+	app.sweepTempDirectory()
 
 	go func() {
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 		<-sigCh
-
-		if len(app.urls) > 0 {
-			app.LogInfo("Shutdown signal received. Waiting for download workers to finish")
-			cancel()
-
-			<-sigCh
+		prompting := app.prompting.Load()
+		if !prompting {
+			app.interrupted.Store(true)
+		}
+		cancel()
+		if prompting {
+			os.Exit(app.exitCode())
 		}
+		app.LogInfo("Shutdown signal received. Waiting for workers to finish, send it again to exit now")
 
-		os.Exit(0)
+		<-sigCh
+		os.Exit(app.exitCode())
 	}()
 
-	if cfg.WriteErrorLog {
-		logFilePath, _, err := FindErrorLogFile()
-		if err != nil {
-			fmt.Println(err.Error())
-			Pause()
-		}
-		f, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-		if err != nil {
-			panic(err)
-		}
-		app.logFile = f
-		defer f.Close()
+	switch command {
+	case "login":
+		app.login(args)
+	case "logout":
+		app.logout(args)
+	case "whoami":
+		app.whoami(args)
+	case "watch":
+		app.watch(args)
+	case "search":
+		app.searchCommand(args)
+	case "info":
+		app.infoCommand(args)
+	case "verify":
+		app.verifyCommand(args)
+	case "scan":
+		app.scanCommand(args)
+	case "upgrade":
+		app.upgradeCommand(args)
+	case "import":
+		app.importCommand(args)
+	default:
+		app.download(args, flags.quit || !interactive)
 	}
 
-	auth := beatport.NewAuth(cfg.Username, cfg.Password, cachePath)
-	bp := beatport.New(beatport.StoreBeatport, cfg.Proxy, auth)
-	bs := beatport.New(beatport.StoreBeatsource, cfg.Proxy, auth)
-
-	if err := auth.LoadCache(); err != nil {
-		if err := auth.Init(bp); err != nil {
-			app.FatalError("beatport", err)
-		}
+	if app.logFile != nil {
+		app.logFile.Close()
 	}
+	os.Exit(app.exitCode())
+}
 
-	app.bp = bp
-	app.bs = bs
-
-	quitFlag := flag.Bool("q", false, "Quit the main loop after finishing")
-
-	flag.Parse()
-	inputArgs := flag.Args()
-
-	for _, arg := range inputArgs {
-		if strings.HasSuffix(arg, ".txt") {
+func (app *application) download(args []string, quit bool) {
+	for _, arg := range args {
+		switch {
+		case arg == "-":
+			app.readUrls(os.Stdin)
+			quit = true
+		case tracklist.IsSupported(arg):
 			app.parseTextFile(arg)
-		} else {
+		default:
 			app.urls = append(app.urls, arg)
 		}
 	}
+	if len(args) == 0 && !stdinIsTerminal() {
+		app.readUrls(os.Stdin)
+		quit = true
+	}
+
+	if len(app.urls) == 0 && !interactive {
+		app.LogError("download", ErrNoUrls)
+		os.Exit(exitUsage)
+	}
 
 	for {
 		if len(app.urls) == 0 {
 			app.mainPrompt()
 		}
 
-		app.pbp = mpb.New(mpb.WithAutoRefresh(), mpb.WithOutput(color.Output))
-		app.logWriter = app.pbp
-		app.activeFiles = make(map[string]struct{}, len(app.urls))
+		app.downloadUrls()
+
+		if quit || app.ctx.Err() != nil {
+			break
+		}
+
+		app.urls = []string{}
+	}
+}
 
+func (app *application) downloadUrls() {
+	app.runDownloads(func() {
 		for _, url := range app.urls {
 			app.globalWorker(func() {
 				app.handleUrl(url)
 			})
 		}
+	})
+}
 
-		app.wg.Wait()
-		app.pbp.Shutdown()
+// runDownloads shows the progress of the workers started by start, and
+// prints the summary once they are done.
+func (app *application) runDownloads(start func()) {
+	app.pbp = mpb.New(mpb.WithAutoRefresh(), mpb.WithOutput(color.Output))
+	app.logWriter = app.pbp
+	app.activeFiles = make(map[string]struct{}, len(app.urls))
 
-		if *quitFlag || ctx.Err() != nil {
-			break
-		}
+	start()
 
-		app.urls = []string{}
+	app.wg.Wait()
+	app.pbp.Shutdown()
+	app.logWriter = os.Stdout
+
+	items := app.results.take()
+	if len(items) > 0 {
+		title := "Summary"
+		if app.config.DryRun {
+			title = "Plan (dry run, the sizes are estimates)"
+		}
+		printSummary(os.Stdout, title, items)
+	}
+	if app.config.ManifestFile != "" {
+		if err := writeManifest(app.config.ManifestFile, items); err != nil {
+			app.LogError("write manifest", err)
+		}
 	}
 }
@@ -105,24 +105,9 @@ func (app *application) downloadSegments(path string, segmentUrls []string, key
 	}
 
 	for _, segmentUrl := range segmentUrls {
-		req, err := http.Get(segmentUrl)
-		if err != nil {
-			return "", err
-		}
-		defer req.Body.Close()
-		if req.StatusCode != http.StatusOK {
-			return "", errors.New(req.Status)
-		}
-		segBytes, err := io.ReadAll(req.Body)
-		if err != nil {
-			return "", err
-		}
-		decSegBytes, err := decryptSegment(segBytes, key)
-		if err != nil {
-			return "", err
-		}
-		_, err = file.Write(decSegBytes)
-		if err != nil {
+		if err := downloadSegment(file, segmentUrl, key); err != nil {
+			file.Close()
+			os.Remove(path)
 			return "", err
 		}
 		if bar != nil {
@@ -131,11 +116,33 @@ func (app *application) downloadSegments(path string, segmentUrls []string, key
 	}
 	err = file.Close()
 	if err != nil {
+		os.Remove(path)
 		return "", err
 	}
 	return path, nil
 }
 
+func downloadSegment(w io.Writer, segmentUrl string, key StreamKey) error {
+	req, err := http.Get(segmentUrl)
+	if err != nil {
+		return err
+	}
+	defer req.Body.Close()
+	if req.StatusCode != http.StatusOK {
+		return errors.New(req.Status)
+	}
+	segBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	decSegBytes, err := decryptSegment(segBytes, key)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(decSegBytes)
+	return err
+}
+
 func remuxToM4A(input, output string) error {
 	cmd := exec.Command("ffmpeg",
 		"-i", input,
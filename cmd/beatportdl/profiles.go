@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+// newApplication creates the config, API clients and filter of the profile
+// on top of the shared state. The empty profile is the base config.
+func (s *state) newApplication(profile string) (*application, error) {
+	cfg, err := s.baseConfig.WithProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyConfigSources(cfg, s.flags); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	bpCachePath, _, err := FindStoreCacheFile(profile, beatport.StoreBeatport)
+	if err != nil {
+		return nil, fmt.Errorf("get executable path: %w", err)
+	}
+	bsCachePath, _, err := FindStoreCacheFile(profile, beatport.StoreBeatsource)
+	if err != nil {
+		return nil, fmt.Errorf("get executable path: %w", err)
+	}
+	bsUsername, bsPassword := cfg.Credentials(string(beatport.StoreBeatsource))
+	cachePassphrase, err := cfg.ResolveTokenCachePassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := newTrackFilter(cfg.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("filters: %w", err)
+	}
+
+	app := &application{
+		state:   s,
+		profile: profile,
+		config:  cfg,
+		bp: beatport.New(
+			beatport.StoreBeatport, cfg.Proxy,
+			beatport.NewAuth(cfg.Username, cfg.Password, bpCachePath),
+		),
+		bs: beatport.New(
+			beatport.StoreBeatsource, cfg.Proxy,
+			beatport.NewAuth(bsUsername, bsPassword, bsCachePath),
+		),
+		filter:  filter,
+		storage: newStorage(cfg),
+	}
+	for _, inst := range []*beatport.Beatport{app.bp, app.bs} {
+		inst.Auth().SetLogger(app.LogInfo)
+		inst.Auth().SetCacheKey(cachePassphrase)
+	}
+	return app, nil
+}
+
+// profileApplication returns the application of the profile, creating it on
+// first use.
+func (app *application) profileApplication(profile string) (*application, error) {
+	app.profilesMutex.Lock()
+	defer app.profilesMutex.Unlock()
+
+	if profileApp, ok := app.profiles[profile]; ok {
+		return profileApp, nil
+	}
+	profileApp, err := app.newApplication(profile)
+	if err != nil {
+		return nil, err
+	}
+	app.profiles[profile] = profileApp
+	profileApp.sweepTempDirectory()
+	return profileApp, nil
+}
+
+// cutProfilePrefix splits a "profile:url" input into the profile name and the URL.
+func cutProfilePrefix(input string) (profile, url string, found bool) {
+	profile, url, found = strings.Cut(input, ":")
+	if !found || profile == "" || strings.HasPrefix(url, "//") {
+		return "", input, false
+	}
+	return profile, url, true
+}
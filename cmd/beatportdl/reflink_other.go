@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func reflinkFile(from, to string) error {
+	return errReflinkUnsupported
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+type resultStatus string
+
+const (
+	resultDownloaded resultStatus = "downloaded"
+	resultUpdated    resultStatus = "updated"
+	resultLinked     resultStatus = "linked"
+	resultSkipped    resultStatus = "skipped"
+	resultFailed     resultStatus = "failed"
+)
+
+var resultStatuses = []resultStatus{resultDownloaded, resultUpdated, resultLinked, resultSkipped, resultFailed}
+
+// result is the outcome of a track, or of a URL that failed before its
+// tracks could be handled.
+type result struct {
+	URL     string         `json:"url"`
+	Store   beatport.Store `json:"store,omitempty"`
+	TrackID int64          `json:"track_id,omitempty"`
+	Name    string         `json:"name,omitempty"`
+	Status  resultStatus   `json:"status"`
+	Step    string         `json:"step,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Quality string         `json:"quality,omitempty"`
+	Bytes   int64          `json:"bytes,omitempty"`
+	Path    string         `json:"path,omitempty"`
+}
+
+type results struct {
+	items []result
+	mutex sync.Mutex
+}
+
+func (r *results) add(item result) {
+	r.mutex.Lock()
+	r.items = append(r.items, item)
+	r.mutex.Unlock()
+}
+
+// take returns the collected results and starts a new collection.
+func (r *results) take() []result {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	items := r.items
+	r.items = nil
+	return items
+}
+
+func (app *application) addTrackResult(inst *beatport.Beatport, track *beatport.Track, status resultStatus, quality, location string) {
+	item := app.trackResult(inst, track, status, quality, location)
+	if status == resultDownloaded {
+		if info, err := os.Stat(location); err == nil {
+			item.Bytes = info.Size()
+		}
+	}
+	app.results.add(item)
+}
+
+func (app *application) trackResult(inst *beatport.Beatport, track *beatport.Track, status resultStatus, quality, location string) result {
+	return result{
+		URL:     track.StoreUrl(),
+		Store:   inst.Store(),
+		TrackID: track.ID,
+		Name:    fmt.Sprintf("%s - %s (%s)", track.Artists.Display(app.config.ArtistsLimit, app.config.ArtistsShortForm), track.Name.String(), track.MixName.String()),
+		Status:  status,
+		Quality: quality,
+		Path:    location,
+	}
+}
+
+func (app *application) addFailedResult(url, step string, err error) {
+	item := result{
+		URL:    url,
+		Status: resultFailed,
+		Step:   step,
+		Error:  err.Error(),
+	}
+	if link, err := (&beatport.Beatport{}).ParseUrl(url); err == nil {
+		item.Store = link.Store
+		if link.Type == beatport.TrackLink {
+			item.TrackID = link.ID
+		}
+	}
+	app.results.add(item)
+}
+
+// printSummary prints the number of tracks per status and the failures.
+func printSummary(w io.Writer, title string, items []result) {
+	counts := make(map[resultStatus]int)
+	var bytes int64
+	var failed []result
+	for _, item := range items {
+		counts[item.Status]++
+		bytes += item.Bytes
+		if item.Status == resultFailed {
+			failed = append(failed, item)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, status := range resultStatuses {
+		fmt.Fprintf(tw, "  %s\t%d\n", strings.ToUpper(string(status[:1]))+string(status[1:]), counts[status])
+	}
+	fmt.Fprintf(tw, "  Size\t%.1f MB\n", float64(bytes)/1e6)
+	tw.Flush()
+
+	if len(failed) > 0 {
+		fmt.Fprintln(w, "\nFailed:")
+		for _, item := range failed {
+			fmt.Fprintf(w, "  %s (%s): %s\n", item.URL, item.Step, item.Error)
+		}
+	}
+}
+
+// writeManifest writes the results as CSV when the path ends with .csv, and as
+// JSON otherwise.
+func writeManifest(path string, items []result) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		w := csv.NewWriter(f)
+		w.Write([]string{"url", "store", "track_id", "name", "status", "step", "error", "quality", "bytes", "path"})
+		for _, item := range items {
+			w.Write([]string{
+				item.URL,
+				string(item.Store),
+				strconv.FormatInt(item.TrackID, 10),
+				item.Name,
+				string(item.Status),
+				item.Step,
+				item.Error,
+				item.Quality,
+				strconv.FormatInt(item.Bytes, 10),
+				item.Path,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if items == nil {
+		items = []result{}
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", " ")
+	return encoder.Encode(items)
+}
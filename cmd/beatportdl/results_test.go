@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	items := []result{
+		{URL: "https://www.beatport.com/track/a/1", TrackID: 1, Status: resultDownloaded, Quality: "FLAC", Bytes: 2e6},
+		{URL: "https://www.beatport.com/track/b/2", TrackID: 2, Status: resultFailed, Step: "handle track", Error: "bad status: 403 Forbidden"},
+	}
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "results.json")
+	if err := writeManifest(jsonPath, items); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1] != items[1] {
+		t.Errorf("json manifest = %+v, expected %+v", decoded, items)
+	}
+
+	csvPath := filepath.Join(dir, "results.csv")
+	if err := writeManifest(csvPath, items); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2][4] != "failed" || records[2][6] != "bad status: 403 Forbidden" {
+		t.Errorf("csv manifest = %v", records)
+	}
+
+	var out bytes.Buffer
+	printSummary(&out, "Summary", items)
+	if !strings.Contains(out.String(), "Failed      1") || !strings.Contains(out.String(), "https://www.beatport.com/track/b/2 (handle track)") {
+		t.Errorf("summary = %q", out.String())
+	}
+}
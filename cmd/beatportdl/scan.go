@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/taglib"
+)
+
+type scanStatus string
+
+const (
+	scanOK         scanStatus = "ok"
+	scanUntagged   scanStatus = "untagged"
+	scanMismatched scanStatus = "mismatched"
+	scanMissing    scanStatus = "missing"
+	scanFailed     scanStatus = "failed"
+)
+
+type scanResult struct {
+	Path      string     `json:"path"`
+	Status    scanStatus `json:"status"`
+	MatchedBy string     `json:"matched_by,omitempty"`
+	TrackID   int64      `json:"track_id,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	Action    string     `json:"action,omitempty"`
+}
+
+// libraryTags are the tags used to match a file to a track.
+type libraryTags struct {
+	trackID string
+	url     string
+	isrc    string
+	title   string
+	artists string
+}
+
+// readLibraryTags reads the tags set with the track_id, track_url,
+// track_isrc, track_name and track_artists tag mappings, falling back to the
+// common tag names.
+func (app *application) readLibraryTags(path string) (*libraryTags, error) {
+	file, err := taglib.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mappings := app.config.TagMappings[strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")]
+	get := func(field, fallback string) string {
+		if property, ok := mappings[field]; ok {
+			if value := file.GetProperty(strings.TrimSuffix(property, rawTagSuffix)); value != "" {
+				return value
+			}
+		}
+		if fallback == "" {
+			return ""
+		}
+		return file.GetProperty(fallback)
+	}
+	return &libraryTags{
+		trackID: get("track_id", ""),
+		url:     get("track_url", ""),
+		isrc:    get("track_isrc", "ISRC"),
+		title:   get("track_name", "TITLE"),
+		artists: get("track_artists", "ARTIST"),
+	}, nil
+}
+
+func (app *application) scanCommand(args []string) {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	storeFlag := fs.String("store", string(beatport.StoreBeatport), "Store to match the files against (beatport, beatsource)")
+	retagFlag := fs.Bool("retag", false, "Rewrite the tags of the matched files with the current tag mappings")
+	renameFlag := fs.Bool("rename", false, "Rename the matched files with the current track file template")
+	allFlag := fs.Bool("all", false, "Also list the files that are tagged and match")
+	jsonFlag := fs.Bool("json", false, "Print the results as JSON")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: beatportdl scan [flags] <directory>...")
+		os.Exit(exitUsage)
+	}
+
+	inst, err := app.storeInstance(beatport.Store(*storeFlag))
+	if err != nil {
+		app.FatalError("scan", err)
+	}
+
+	var paths []string
+	for _, dir := range fs.Args() {
+		files, err := audioFiles(dir)
+		if err != nil {
+			app.FatalError("scan", err)
+		}
+		paths = append(paths, files...)
+	}
+
+	results := make([]scanResult, len(paths))
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, app.config.MaxGlobalWorkers)
+	started := 0
+	for i, path := range paths {
+		if app.ctx.Err() != nil {
+			break
+		}
+		started++
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = app.scanFile(inst, path, *retagFlag, *renameFlag)
+			if results[i].Status == scanFailed {
+				app.failed.Add(1)
+			} else {
+				app.succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	results = results[:started]
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	if *jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", " ")
+		if err := encoder.Encode(results); err != nil {
+			app.FatalError("scan", err)
+		}
+		return
+	}
+
+	counts := make(map[scanStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+		if result.Status == scanOK && !*allFlag && result.Action == "" {
+			continue
+		}
+		fmt.Printf("[%s] %s\n", result.Status, result.Path)
+		if result.URL != "" {
+			fmt.Printf("  %s (matched by %s)\n", result.URL, result.MatchedBy)
+		}
+		if result.Reason != "" {
+			fmt.Printf("  %s\n", result.Reason)
+		}
+		if result.Action != "" {
+			fmt.Printf("  %s\n", result.Action)
+		}
+	}
+	fmt.Printf(
+		"Scanned %d files: %d ok, %d untagged, %d mismatched, %d missing, %d failed\n",
+		len(results), counts[scanOK], counts[scanUntagged], counts[scanMismatched], counts[scanMissing], counts[scanFailed],
+	)
+}
+
+func (app *application) scanFile(inst *beatport.Beatport, path string, retag, rename bool) scanResult {
+	result := scanResult{Path: path}
+	tags, err := app.readLibraryTags(path)
+	if err != nil {
+		result.Status = scanFailed
+		result.Reason = fmt.Sprintf("read tags: %v", err)
+		return result
+	}
+
+	track, matchedBy, err := app.matchLibraryFile(inst, path, tags)
+	if err != nil {
+		result.Status = scanFailed
+		result.Reason = err.Error()
+		return result
+	}
+	if track == nil {
+		result.Status = scanMissing
+		result.Reason = "no matching track found"
+		return result
+	}
+	result.TrackID = track.ID
+	result.URL = track.StoreUrl()
+	result.MatchedBy = matchedBy
+
+	switch {
+	case matchedBy != "id":
+		result.Status = scanUntagged
+		result.Reason = "the file has no track id tag"
+	case tags.isrc != "" && track.ISRC != "" && !strings.EqualFold(tags.isrc, track.ISRC):
+		result.Status = scanMismatched
+		result.Reason = fmt.Sprintf("ISRC %s does not match %s", tags.isrc, track.ISRC)
+	case tags.title != "" && !titleMatches(tags.title, track):
+		result.Status = scanMismatched
+		result.Reason = fmt.Sprintf("title %q does not match %q", tags.title, trackTitle(track))
+	default:
+		result.Status = scanOK
+	}
+
+	if retag || rename {
+		actions, err := app.updateLibraryFile(inst, track, path, retag, rename)
+		if err != nil {
+			result.Status = scanFailed
+			result.Reason = err.Error()
+		}
+		result.Action = strings.Join(actions, ", ")
+	}
+	return result
+}
+
+// matchLibraryFile finds the track of the file by the track id or URL tags,
+// then by ISRC, then by an artist and title search.
+func (app *application) matchLibraryFile(inst *beatport.Beatport, path string, tags *libraryTags) (*beatport.Track, string, error) {
+	id, _ := strconv.ParseInt(strings.TrimSpace(tags.trackID), 10, 64)
+	if id == 0 && tags.url != "" {
+		if link, err := inst.ParseUrl(tags.url); err == nil && link.Type == beatport.TrackLink {
+			id = link.ID
+		}
+	}
+	if id != 0 {
+		track, err := inst.GetTrack(id)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetch track %d: %w", id, err)
+		}
+		return track, "id", nil
+	}
+
+	if tags.isrc != "" {
+		tracks, err := inst.GetTracksByISRC(tags.isrc)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetch tracks by isrc: %w", err)
+		}
+		if match := matchSearchResult(tags.title, tags.artists, tracks); match != nil {
+			return match, "isrc", nil
+		}
+		if len(tracks) > 0 {
+			return &tracks[0], "isrc", nil
+		}
+	}
+
+	title, artists := tags.title, tags.artists
+	if title == "" {
+		// Untagged files are matched by the "Artists - Title (Mix)" file name
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		name = strings.TrimLeftFunc(name, func(r rune) bool {
+			return unicode.IsDigit(r) || r == '.' || unicode.IsSpace(r)
+		})
+		artists, title, _ = strings.Cut(name, " - ")
+		if title == "" {
+			title, artists = artists, ""
+		}
+	}
+	results, err := inst.Search(strings.TrimSpace(artists+" "+title), beatport.SearchOptions{
+		Types: []beatport.SearchType{beatport.SearchTracks},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("search: %w", err)
+	}
+	if match := matchSearchResult(title, artists, results.Tracks); match != nil {
+		return match, "search", nil
+	}
+	return nil, "", nil
+}
+
+// updateLibraryFile rewrites the tags of a matched file and renames it with
+// the track file template, without downloading the audio again.
+func (app *application) updateLibraryFile(inst *beatport.Beatport, track *beatport.Track, path string, retag, rename bool) ([]string, error) {
+	release, err := inst.GetRelease(track.Release.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch release: %w", err)
+	}
+	track.Release = *release
+
+	var actions []string
+	if retag {
+		if app.config.DryRun {
+			actions = append(actions, "would retag")
+		} else {
+			if err := app.retagLibraryFile(path, track); err != nil {
+				return actions, fmt.Errorf("retag: %w", err)
+			}
+			actions = append(actions, "retagged")
+		}
+	}
+
+	if rename {
+		newPath := app.trackPath(filepath.Dir(path), track, filepath.Ext(path))
+		if newPath == path {
+			return actions, nil
+		}
+		if app.config.DryRun {
+			if _, err := os.Stat(newPath); err == nil {
+				return actions, fmt.Errorf("rename: %s: %w", newPath, ErrTrackFileExists)
+			}
+			return append(actions, "would rename to "+filepath.Base(newPath)), nil
+		}
+		if err := renameNoReplace(path, newPath); err != nil {
+			return actions, fmt.Errorf("rename: %w", err)
+		}
+		actions = append(actions, "renamed to "+filepath.Base(newPath))
+	}
+	return actions, nil
+}
+
+// renameNoReplace moves the file to newPath unless it exists. The path is
+// claimed with a hard link, or an empty file on file systems without them or
+// across file systems, so parallel renames to the same path cannot replace
+// each other's file.
+func renameNoReplace(path, newPath string) error {
+	if err := os.Link(path, newPath); err == nil {
+		return os.Remove(path)
+	} else if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s: %w", newPath, ErrTrackFileExists)
+	}
+
+	file, err := os.OpenFile(newPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s: %w", newPath, ErrTrackFileExists)
+		}
+		return err
+	}
+	file.Close()
+	if err := moveIntoPlace(path, newPath); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	return nil
+}
+
+// retagLibraryFile tags a copy of the file and replaces the file once the
+// tags are written.
+func (app *application) retagLibraryFile(path string, track *beatport.Track) error {
+	temp, err := app.stagingFile(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+	if err := copyFile(path, temp); err != nil {
+		return err
+	}
+	if err := app.tagLibraryFile(temp, track); err != nil {
+		return err
+	}
+	return moveIntoPlace(temp, path)
+}
+
+// tagLibraryFile tags a file outside of a download, fetching the cover when
+// the tags need it.
+func (app *application) tagLibraryFile(path string, track *beatport.Track) error {
+	var cover *coverArt
+	if app.requireCover(true, false) || filepath.Ext(path) == ".m4a" {
+		var err error
+		if cover, err = app.downloadCover(track.Release.Image, true, false); err != nil {
+			return fmt.Errorf("download cover: %w", err)
+		}
+		defer cover.remove()
+	}
+	return app.tagTrack(path, track, cover.embedPath())
+}
+
+func trackTitle(track *beatport.Track) string {
+	return fmt.Sprintf("%s (%s)", track.Name.String(), track.MixName.String())
+}
+
+// normalizeTitle lowercases the string and drops everything but letters and digits.
+func normalizeTitle(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func titleMatches(title string, track *beatport.Track) bool {
+	normalized := normalizeTitle(title)
+	return normalized == normalizeTitle(trackTitle(track)) || normalized == normalizeTitle(track.Name.String())
+}
+
+// matchSearchResult returns the first track with the title that shares an
+// artist with the artists tag, or any artist when the tag is empty.
+func matchSearchResult(title, artists string, tracks []beatport.Track) *beatport.Track {
+	normalizedArtists := normalizeTitle(artists)
+	for i := range tracks {
+		track := &tracks[i]
+		if !titleMatches(title, track) {
+			continue
+		}
+		if normalizedArtists == "" {
+			return track
+		}
+		for _, artist := range track.Artists {
+			if strings.Contains(normalizedArtists, normalizeTitle(artist.Name)) {
+				return track
+			}
+		}
+	}
+	return nil
+}
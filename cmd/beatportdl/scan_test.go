@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+func TestMatchSearchResult(t *testing.T) {
+	tracks := []beatport.Track{
+		{ID: 1, Name: "Losing It", MixName: "Radio Edit", Artists: beatport.Artists{{Name: "FISHER"}}},
+		{ID: 2, Name: "Losing It", MixName: "Original Mix", Artists: beatport.Artists{{Name: "Someone Else"}}},
+		{ID: 3, Name: "Losing It", MixName: "Original Mix", Artists: beatport.Artists{{Name: "FISHER"}}},
+	}
+
+	tests := []struct {
+		title   string
+		artists string
+		want    int64
+	}{
+		{"Losing It (Original Mix)", "Fisher", 3},
+		{"losing it - original mix", "FISHER, Chris Lake", 3},
+		{"Losing It (Radio Edit)", "", 1},
+		{"Losing It", "Fisher", 1},
+		{"Losing It (Extended Mix)", "Fisher", 0},
+		{"Losing It (Original Mix)", "Nobody", 0},
+	}
+	for _, tt := range tests {
+		var got int64
+		if match := matchSearchResult(tt.title, tt.artists, tracks); match != nil {
+			got = match.ID
+		}
+		if got != tt.want {
+			t.Errorf("matchSearchResult(%q, %q) = %d, want %d", tt.title, tt.artists, got, tt.want)
+		}
+	}
+}
+
+func TestRenameNoReplace(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "Artist - Track (Original Mix).flac")
+
+	var sources []string
+	for _, name := range []string{"a.flac", "b.flac", "c.flac"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, path)
+	}
+
+	errs := make([]error, len(sources))
+	wg := sync.WaitGroup{}
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = renameNoReplace(source, target)
+		}()
+	}
+	wg.Wait()
+
+	var renamed string
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if renamed != "" {
+				t.Fatalf("both %s and %s were renamed", renamed, sources[i])
+			}
+			renamed = sources[i]
+		case errors.Is(err, ErrTrackFileExists):
+			if _, err := os.Stat(sources[i]); err != nil {
+				t.Errorf("%s was removed: %v", sources[i], err)
+			}
+		default:
+			t.Errorf("renameNoReplace(%s) failed: %v", sources[i], err)
+		}
+	}
+	if renamed == "" {
+		t.Fatal("no file was renamed")
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != filepath.Base(renamed) {
+		t.Errorf("target = %q, want the content of %s", data, renamed)
+	}
+	if _, err := os.Stat(renamed); !os.IsNotExist(err) {
+		t.Errorf("%s still exists: %v", renamed, err)
+	}
+}
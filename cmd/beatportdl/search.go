@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+type searchResult struct {
+	Type  beatport.SearchType `json:"type"`
+	ID    int64               `json:"id"`
+	Title string              `json:"title"`
+	URL   string              `json:"url"`
+}
+
+// searchOutput is the JSON output of the search command. The search endpoint
+// does not report a total, PageResults is the number of results on the page.
+type searchOutput struct {
+	Query       string         `json:"query"`
+	Store       beatport.Store `json:"store"`
+	Page        int            `json:"page"`
+	PageResults int            `json:"page_results"`
+	Results     []searchResult `json:"results"`
+}
+
+func (app *application) flattenSearchResults(results *beatport.SearchResults) []searchResult {
+	var flat []searchResult
+	for _, track := range results.Tracks {
+		flat = append(flat, searchResult{
+			Type: beatport.SearchTracks,
+			ID:   track.ID,
+			Title: fmt.Sprintf(
+				"%s - %s (%s) [%s]",
+				track.Artists.Display(app.config.ArtistsLimit, app.config.ArtistsShortForm),
+				track.Name.String(),
+				track.MixName.String(),
+				track.Length,
+			),
+			URL: track.StoreUrl(),
+		})
+	}
+	for _, release := range results.Releases {
+		flat = append(flat, searchResult{
+			Type: beatport.SearchReleases,
+			ID:   release.ID,
+			Title: fmt.Sprintf(
+				"%s - %s [%s]",
+				release.Artists.Display(app.config.ArtistsLimit, app.config.ArtistsShortForm),
+				release.Name.String(),
+				release.Label.Name,
+			),
+			URL: release.StoreUrl(),
+		})
+	}
+	for _, artist := range results.Artists {
+		flat = append(flat, searchResult{
+			Type:  beatport.SearchArtists,
+			ID:    artist.ID,
+			Title: artist.Name,
+			URL:   artist.StoreUrl(),
+		})
+	}
+	for _, label := range results.Labels {
+		flat = append(flat, searchResult{
+			Type:  beatport.SearchLabels,
+			ID:    label.ID,
+			Title: label.Name,
+			URL:   label.StoreUrl(),
+		})
+	}
+	for _, chart := range results.Charts {
+		flat = append(flat, searchResult{
+			Type:  beatport.SearchCharts,
+			ID:    chart.ID,
+			Title: fmt.Sprintf("%s [%s]", chart.Name, chart.Person.OwnerName),
+			URL:   chart.StoreUrl(),
+		})
+	}
+	for _, playlist := range results.Playlists {
+		flat = append(flat, searchResult{
+			Type:  beatport.SearchPlaylists,
+			ID:    playlist.ID,
+			Title: fmt.Sprintf("%s [%d tracks]", playlist.Name, playlist.TrackCount),
+			URL:   playlist.StoreUrl(),
+		})
+	}
+	return flat
+}
+
+func printSearchResults(results []searchResult, withUrls bool) {
+	var lastType beatport.SearchType
+	for i, result := range results {
+		if result.Type != lastType {
+			if lastType != "" {
+				fmt.Println()
+			}
+			count := 0
+			for _, r := range results {
+				if r.Type == result.Type {
+					count++
+				}
+			}
+			fmt.Printf("[ %s (%d) ]\n", strings.ToUpper(string(result.Type[:1]))+string(result.Type[1:]), count)
+			lastType = result.Type
+		}
+		if withUrls {
+			fmt.Printf("%2d. %s - %s\n", i+1, result.Title, result.URL)
+		} else {
+			fmt.Printf("%2d. %s\n", i+1, result.Title)
+		}
+	}
+}
+
+var (
+	searchTagRegex    = regexp.MustCompile(`@\w+`)
+	searchOptionRegex = regexp.MustCompile(`\b(bpm|key|genre|sort|page):(\S+)`)
+)
+
+// parseSearchInput extracts the store and type tags (@beatsource, @labels)
+// and the bpm:, key:, genre:, sort: and page: options from a prompt query.
+func parseSearchInput(input string) (query string, store beatport.Store, options beatport.SearchOptions, err error) {
+	store = beatport.StoreBeatport
+	options.Page = 1
+
+	for _, tag := range searchTagRegex.FindAllString(input, -1) {
+		tag = strings.TrimPrefix(tag, "@")
+		switch beatport.Store(tag) {
+		case beatport.StoreBeatport, beatport.StoreBeatsource:
+			store = beatport.Store(tag)
+			continue
+		}
+		searchType, err := beatport.ParseSearchType(tag)
+		if err != nil {
+			return "", "", options, err
+		}
+		options.Types = append(options.Types, searchType)
+	}
+	input = searchTagRegex.ReplaceAllString(input, "")
+
+	var filters config.Filters
+	for _, match := range searchOptionRegex.FindAllStringSubmatch(input, -1) {
+		switch match[1] {
+		case "bpm":
+			if filters.BPMMin, filters.BPMMax, err = parseBPMRange(match[2]); err != nil {
+				return "", "", options, err
+			}
+		case "key":
+			filters.Keys = splitList(match[2])
+		case "genre":
+			if options.GenreID, err = strconv.ParseInt(match[2], 10, 64); err != nil {
+				filters.Genres = splitList(strings.ReplaceAll(match[2], "_", " "))
+			}
+		case "sort":
+			options.OrderBy = match[2]
+		case "page":
+			if options.Page, err = strconv.Atoi(match[2]); err != nil || options.Page < 1 {
+				return "", "", options, fmt.Errorf("invalid page: %s", match[2])
+			}
+		}
+	}
+	input = searchOptionRegex.ReplaceAllString(input, "")
+
+	if options.Filter, err = newTrackFilter(filters); err != nil {
+		return "", "", options, err
+	}
+
+	return strings.Join(strings.Fields(input), " "), store, options, nil
+}
+
+func (app *application) searchCommand(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	typeFlag := fs.String("type", "", "Comma separated result types (tracks, releases, artists, labels, charts, playlists)")
+	storeFlag := fs.String("store", string(beatport.StoreBeatport), "Store to search (beatport, beatsource)")
+	pageFlag := fs.Int("page", 1, "Results page")
+	perPageFlag := fs.Int("per-page", 0, "Results per page")
+	sortFlag := fs.String("sort", beatport.DefaultSearchOrder, "Sort order (e.g. -publish_date, -release_date, name)")
+	genreFlag := fs.Int64("genre", 0, "Genre ID")
+	bpmFlag := fs.String("bpm", "", "BPM range (e.g. 120-128)")
+	keyFlag := fs.String("key", "", "Comma separated keys (e.g. 8A,9A or Am)")
+	jsonFlag := fs.Bool("json", false, "Print the results as JSON")
+	fs.Parse(args)
+
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		fmt.Println("Usage: beatportdl search [flags] <query>")
+		os.Exit(exitUsage)
+	}
+
+	options := beatport.SearchOptions{
+		Page:    *pageFlag,
+		PerPage: *perPageFlag,
+		OrderBy: *sortFlag,
+		GenreID: *genreFlag,
+	}
+	for _, t := range splitList(*typeFlag) {
+		searchType, err := beatport.ParseSearchType(t)
+		if err != nil {
+			app.FatalError("search", err)
+		}
+		options.Types = append(options.Types, searchType)
+	}
+
+	var err error
+	filters := config.Filters{Keys: splitList(*keyFlag)}
+	if *bpmFlag != "" {
+		if filters.BPMMin, filters.BPMMax, err = parseBPMRange(*bpmFlag); err != nil {
+			app.FatalError("search", err)
+		}
+	}
+	if options.Filter, err = newTrackFilter(filters); err != nil {
+		app.FatalError("search", err)
+	}
+
+	inst, err := app.storeInstance(beatport.Store(*storeFlag))
+	if err != nil {
+		app.FatalError("search", err)
+	}
+
+	results, err := inst.Search(query, options)
+	if err != nil {
+		app.FatalError("search", err)
+	}
+	flat := app.flattenSearchResults(results)
+
+	if *jsonFlag {
+		output := searchOutput{
+			Query:       query,
+			Store:       beatport.Store(*storeFlag),
+			Page:        options.Page,
+			PageResults: len(flat),
+			Results:     flat,
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", " ")
+		if err := encoder.Encode(output); err != nil {
+			app.FatalError("search", err)
+		}
+		return
+	}
+
+	if len(flat) == 0 {
+		fmt.Println("No results found")
+		return
+	}
+	printSearchResults(flat, true)
+}
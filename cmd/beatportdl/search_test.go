@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+func TestParseSearchInput(t *testing.T) {
+	query, store, options, err := parseSearchInput("adam  beyer @beatsource @tracks @release bpm:128-132 key:8A genre:5 sort:-bpm page:2")
+	if err != nil {
+		t.Fatalf("parseSearchInput() failed: %v", err)
+	}
+	if query != "adam beyer" {
+		t.Errorf("query = %q", query)
+	}
+	if store != beatport.StoreBeatsource {
+		t.Errorf("store = %q", store)
+	}
+	if want := []beatport.SearchType{beatport.SearchTracks, beatport.SearchReleases}; !slices.Equal(options.Types, want) {
+		t.Errorf("Types = %v, want %v", options.Types, want)
+	}
+	if options.Filter.BPMMin != 128 || options.Filter.BPMMax != 132 {
+		t.Errorf("BPM = %d-%d", options.Filter.BPMMin, options.Filter.BPMMax)
+	}
+	if len(options.Filter.Keys) != 1 {
+		t.Errorf("Keys = %v", options.Filter.Keys)
+	}
+	if options.GenreID != 5 || options.OrderBy != "-bpm" || options.Page != 2 {
+		t.Errorf("GenreID = %d, OrderBy = %q, Page = %d", options.GenreID, options.OrderBy, options.Page)
+	}
+
+	query, store, options, err = parseSearchInput("strobe genre:deep_house")
+	if err != nil {
+		t.Fatalf("parseSearchInput() failed: %v", err)
+	}
+	if query != "strobe" || store != beatport.StoreBeatport || options.Page != 1 || len(options.Types) != 0 {
+		t.Errorf("parseSearchInput() = %q, %q, %+v", query, store, options)
+	}
+	if !slices.Equal(options.Filter.Genres, []string{"deep house"}) {
+		t.Errorf("Genres = %v", options.Filter.Genres)
+	}
+
+	for _, input := range []string{"strobe @songs", "strobe page:0", "strobe bpm:fast", "strobe key:H"} {
+		if _, _, _, err := parseSearchInput(input); err == nil {
+			t.Errorf("parseSearchInput(%q) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/storage"
+)
+
+func newStorage(cfg *config.AppConfig) storage.Storage {
+	switch cfg.Storage.Type {
+	case "s3":
+		return storage.NewS3(storage.S3Options{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			Prefix:          cfg.Storage.S3.Prefix,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			PathStyle:       cfg.Storage.S3.PathStyle,
+		})
+	case "sftp":
+		return storage.NewSFTP(storage.SFTPOptions{
+			Host:         cfg.Storage.SFTP.Host,
+			Port:         cfg.Storage.SFTP.Port,
+			User:         cfg.Storage.SFTP.User,
+			IdentityFile: cfg.Storage.SFTP.IdentityFile,
+			Directory:    cfg.Storage.SFTP.Directory,
+		})
+	default:
+		return storage.NewLocal(cfg.DownloadsDirectory)
+	}
+}
+
+// storageKey returns the key of a file staged in the downloads directory.
+func (app *application) storageKey(location string) (string, error) {
+	rel, err := filepath.Rel(app.config.DownloadsDirectory, location)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the downloads directory", location)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// fileExists reports whether the file exists in the staging directory or in
+// the storage backend.
+func (app *application) fileExists(location string) (bool, error) {
+	if _, err := os.Stat(location); err == nil {
+		return true, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if app.storage.IsLocal() {
+		return false, nil
+	}
+	key, err := app.storageKey(location)
+	if err != nil {
+		return false, err
+	}
+	return app.storage.Exists(context.Background(), key)
+}
+
+// stageFile copies an existing file, from the downloads directory or the
+// storage backend, to the temp file its tags are updated in.
+func (app *application) stageFile(location, temp string) error {
+	if _, err := os.Stat(location); err == nil || app.storage.IsLocal() {
+		return copyFile(location, temp)
+	}
+	key, err := app.storageKey(location)
+	if err != nil {
+		return err
+	}
+	return app.storage.Get(context.Background(), key, temp)
+}
+
+// commitFile stores the staged file in the storage backend. The uploads are
+// not bound to the app context so that the files finished on shutdown are
+// still committed.
+func (app *application) commitFile(location string) error {
+	if location == "" || app.storage.IsLocal() || app.config.DryRun {
+		return nil
+	}
+	key, err := app.storageKey(location)
+	if err != nil {
+		return err
+	}
+	return app.storage.Put(context.Background(), key, location)
+}
+
+// removeStagedFile removes the local copy of a file committed to a remote backend.
+func (app *application) removeStagedFile(location string) {
+	if location == "" || app.storage.IsLocal() || app.config.DryRun {
+		return
+	}
+	os.Remove(location)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+const (
+	syncManifestFilename = ".beatportdl-sync.json"
+	syncArchiveDirectory = "_archive"
+)
+
+type syncManifest struct {
+	Store beatport.Store    `json:"store"`
+	Type  beatport.LinkType `json:"type"`
+	ID    int64             `json:"id"`
+	Items []syncItem        `json:"items"`
+}
+
+type syncItem struct {
+	TrackID  int64  `json:"track_id"`
+	Position int    `json:"position"`
+	Path     string `json:"path"`
+}
+
+// contextSync tracks the files of a playlist or chart directory between runs,
+// so that only new items are downloaded and moved items are renamed in place.
+type contextSync struct {
+	link      *beatport.Link
+	directory string
+	previous  map[int64]syncItem
+	current   map[int64]syncItem
+	renames   map[int64]syncRename
+	// listed are the tracks of the fetched listing, failed is set when one
+	// of them could not be downloaded
+	listed map[int64]bool
+	failed bool
+	mutex  sync.Mutex
+}
+
+type syncRename struct {
+	from syncItem
+	to   string
+}
+
+func (app *application) newContextSync(link *beatport.Link, directory string) (*contextSync, error) {
+	s := &contextSync{
+		link:      link,
+		directory: directory,
+		previous:  make(map[int64]syncItem),
+		current:   make(map[int64]syncItem),
+		renames:   make(map[int64]syncRename),
+		listed:    make(map[int64]bool),
+	}
+
+	data, err := os.ReadFile(filepath.Join(directory, syncManifestFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read sync manifest: %w", err)
+	}
+
+	var manifest syncManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("decode sync manifest: %w", err)
+	}
+	for _, item := range manifest.Items {
+		s.previous[item.TrackID] = item
+	}
+
+	return s, nil
+}
+
+// list marks the track as part of the listing, before it is processed.
+func (s *contextSync) list(trackID int64) {
+	s.mutex.Lock()
+	s.listed[trackID] = true
+	s.mutex.Unlock()
+}
+
+// fail marks the run as incomplete, so that no items are removed.
+func (s *contextSync) fail() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.failed = true
+	s.mutex.Unlock()
+}
+
+func (s *contextSync) record(trackID int64, position int, path string) {
+	s.mutex.Lock()
+	s.current[trackID] = syncItem{
+		TrackID:  trackID,
+		Position: position,
+		Path:     s.relPath(path),
+	}
+	s.mutex.Unlock()
+}
+
+func (s *contextSync) relPath(path string) string {
+	relPath, err := filepath.Rel(s.directory, path)
+	if err != nil {
+		relPath = path
+	}
+	return filepath.ToSlash(relPath)
+}
+
+func (s *contextSync) absPath(item syncItem) string {
+	return filepath.Join(s.directory, filepath.FromSlash(item.Path))
+}
+
+// syncTrack reports whether the track was already downloaded by a previous run.
+// If its position has changed, the file is queued for renaming.
+func (app *application) syncTrack(s *contextSync, track *beatport.Track) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	prev, ok := s.previous[track.ID]
+	if !ok {
+		return false
+	}
+
+	oldPath := s.absPath(prev)
+	if _, err := os.Stat(oldPath); err != nil {
+		return false
+	}
+
+	newPath := app.trackPath(filepath.Dir(oldPath), track, filepath.Ext(oldPath))
+	if newPath != oldPath {
+		s.renames[track.ID] = syncRename{from: prev, to: newPath}
+	}
+
+	s.current[track.ID] = syncItem{
+		TrackID:  track.ID,
+		Position: track.Position,
+		Path:     s.relPath(newPath),
+	}
+	return true
+}
+
+// applyRenames moves every queued file to a temporary name first, so that
+// tracks which swapped positions don't collide with each other.
+func (app *application) applyRenames(s *contextSync) {
+	staged := make(map[int64]string, len(s.renames))
+	for id, rename := range s.renames {
+		oldPath := s.absPath(rename.from)
+		tempPath := oldPath + ".sync"
+		if err := os.Rename(oldPath, tempPath); err != nil {
+			app.errorLogWrapper(s.link.Original, "rename moved item", err)
+			s.current[id] = rename.from
+			continue
+		}
+		staged[id] = tempPath
+	}
+
+	for id, tempPath := range staged {
+		rename := s.renames[id]
+		oldPath := s.absPath(rename.from)
+		if _, err := os.Stat(rename.to); err == nil {
+			app.errorLogWrapper(s.link.Original, "rename moved item", fmt.Errorf("%s: %w", rename.to, ErrTrackFileExists))
+			os.Rename(tempPath, oldPath)
+			s.current[id] = rename.from
+			continue
+		}
+		if err := os.Rename(tempPath, rename.to); err != nil {
+			app.errorLogWrapper(s.link.Original, "rename moved item", err)
+			os.Rename(tempPath, oldPath)
+			s.current[id] = rename.from
+			continue
+		}
+		app.infoLogWrapper(
+			s.link.Original,
+			fmt.Sprintf("moved %s from position %d to %d", filepath.Base(rename.to), rename.from.Position, s.current[id].Position),
+		)
+	}
+}
+
+// finishContextSync renames moved items, handles the items that were removed
+// from the playlist since the last run and writes the new manifest. Items
+// that are still listed but were filtered, failed or not processed are kept
+// as they are, and nothing is removed when the run was incomplete.
+func (app *application) finishContextSync(s *contextSync) error {
+	if app.config.DryRun {
+		return nil
+	}
+
+	app.applyRenames(s)
+
+	complete := !s.failed && app.ctx.Err() == nil
+	for id, item := range s.previous {
+		if _, ok := s.current[id]; ok {
+			continue
+		}
+		if s.listed[id] || !complete {
+			s.current[id] = item
+			continue
+		}
+
+		path := s.absPath(item)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		switch app.config.PlaylistSyncRemoved {
+		case "keep":
+			s.current[id] = item
+		case "delete":
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("delete removed item: %w", err)
+			}
+			app.cleanup(filepath.Dir(path))
+		case "archive":
+			archivePath := filepath.Join(s.directory, syncArchiveDirectory, filepath.FromSlash(item.Path))
+			if err := CreateDirectory(filepath.Dir(archivePath)); err != nil {
+				return fmt.Errorf("archive removed item: %w", err)
+			}
+			if err := os.Rename(path, archivePath); err != nil {
+				return fmt.Errorf("archive removed item: %w", err)
+			}
+			app.cleanup(filepath.Dir(path))
+		}
+	}
+
+	manifest := syncManifest{
+		Store: s.link.Store,
+		Type:  s.link.Type,
+		ID:    s.link.ID,
+	}
+	for _, item := range s.current {
+		manifest.Items = append(manifest.Items, item)
+	}
+	sort.Slice(manifest.Items, func(i, j int) bool {
+		return manifest.Items[i].Position < manifest.Items[j].Position
+	})
+
+	data, err := json.MarshalIndent(manifest, "", " ")
+	if err != nil {
+		return fmt.Errorf("encode sync manifest: %w", err)
+	}
+	manifestPath := filepath.Join(s.directory, syncManifestFilename)
+	tempPath := manifestPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("write sync manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, manifestPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("write sync manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/storage"
+)
+
+func TestContextSync(t *testing.T) {
+	dir := t.TempDir()
+	s := &state{ctx: context.Background(), logWriter: os.Stdout}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{
+		config: &config.AppConfig{
+			DownloadsDirectory:  dir,
+			TrackFileTemplate:   "{position}",
+			TrackNumberPadding:  2,
+			PlaylistSyncRemoved: "archive",
+		},
+		state:   s,
+		storage: storage.NewLocal(dir),
+	}
+	link := &beatport.Link{Type: beatport.PlaylistLink, ID: 1, Store: beatport.StoreBeatport}
+
+	first, err := app.newContextSync(link, dir)
+	if err != nil {
+		t.Fatalf("newContextSync() failed: %v", err)
+	}
+	for position, id := range []int64{10, 20, 30} {
+		path := filepath.Join(dir, beatport.NumberWithPadding(position+1, 3, 2)+".flac")
+		if err := os.WriteFile(path, []byte{byte(id)}, 0600); err != nil {
+			t.Fatal(err)
+		}
+		first.record(id, position+1, path)
+	}
+	if err := app.finishContextSync(first); err != nil {
+		t.Fatalf("finishContextSync() failed: %v", err)
+	}
+
+	second, err := app.newContextSync(link, dir)
+	if err != nil {
+		t.Fatalf("newContextSync() failed: %v", err)
+	}
+	for position, id := range []int64{20, 10, 40} {
+		second.list(id)
+		track := &beatport.Track{ID: id, Position: position + 1, PositionTotal: 3}
+		synced := app.syncTrack(second, track)
+		if synced != (id != 40) {
+			t.Errorf("syncTrack(%d) = %v", id, synced)
+		}
+	}
+	if err := app.finishContextSync(second); err != nil {
+		t.Fatalf("finishContextSync() failed: %v", err)
+	}
+
+	expected := map[string]byte{
+		"01.flac":          20,
+		"02.flac":          10,
+		"_archive/03.flac": 30,
+	}
+	for name, id := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("read %s: %v", name, err)
+			continue
+		}
+		if len(data) != 1 || data[0] != id {
+			t.Errorf("%s contains track %v, expected %d", name, data, id)
+		}
+	}
+}
+
+func TestContextSyncKeepsUnprocessedItems(t *testing.T) {
+	dir := t.TempDir()
+	s := &state{ctx: context.Background(), logWriter: os.Stdout}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{
+		config: &config.AppConfig{
+			DownloadsDirectory:  dir,
+			TrackFileTemplate:   "{position}",
+			TrackNumberPadding:  2,
+			PlaylistSyncRemoved: "delete",
+		},
+		state:   s,
+		storage: storage.NewLocal(dir),
+	}
+	link := &beatport.Link{Type: beatport.PlaylistLink, ID: 1, Store: beatport.StoreBeatport}
+
+	first, err := app.newContextSync(link, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for position, id := range []int64{10, 20, 30} {
+		path := filepath.Join(dir, beatport.NumberWithPadding(position+1, 3, 2)+".flac")
+		if err := os.WriteFile(path, []byte{byte(id)}, 0600); err != nil {
+			t.Fatal(err)
+		}
+		first.record(id, position+1, path)
+	}
+	if err := app.finishContextSync(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// 20 is filtered or failed before it is synced, 30 left the playlist
+	// but the run failed, so neither is removed
+	second, err := app.newContextSync(link, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{10, 20} {
+		second.list(id)
+	}
+	app.syncTrack(second, &beatport.Track{ID: 10, Position: 1, PositionTotal: 2})
+	second.fail()
+	if err := app.finishContextSync(second); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"01.flac", "02.flac", "03.flac"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was removed: %v", name, err)
+		}
+	}
+
+	// A complete run only removes the item that is not listed
+	third, err := app.newContextSync(link, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{10, 20} {
+		third.list(id)
+	}
+	app.syncTrack(third, &beatport.Track{ID: 10, Position: 1, PositionTotal: 2})
+	if err := app.finishContextSync(third); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "02.flac")); err != nil {
+		t.Errorf("listed item was removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "03.flac")); !os.IsNotExist(err) {
+		t.Errorf("removed item was kept: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	tempDirectoryName = ".beatportdl-tmp"
+	// partialFilePrefix marks the files written next to their location when
+	// the temp directory is on another file system
+	partialFilePrefix = ".beatportdl-partial-"
+	// staleTempAge is how long a temp file has to be left untouched before it
+	// is considered a leftover of an interrupted run
+	staleTempAge = 6 * time.Hour
+)
+
+// tempDirectory is where the tracks and covers are written while they are
+// downloaded, remuxed and tagged. It is inside the downloads directory, so
+// that the finished files are moved into place with a rename.
+func (app *application) tempDirectory() string {
+	return filepath.Join(app.config.DownloadsDirectory, tempDirectoryName)
+}
+
+func (app *application) tempFile(ext string) (string, error) {
+	dir := app.tempDirectory()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("create temp directory: %w", err)
+	}
+	return filepath.Join(dir, uuid.New().String()+ext), nil
+}
+
+// sweepTempDirectory removes the partial files left behind by the runs that
+// were killed or crashed.
+func (app *application) sweepTempDirectory() {
+	entries, err := os.ReadDir(app.tempDirectory())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < staleTempAge {
+			continue
+		}
+		path := filepath.Join(app.tempDirectory(), entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			app.logger.Warn("remove stale temp file", "path", path, "error", err)
+			continue
+		}
+		app.logger.Debug("removed stale temp file", "path", path)
+	}
+}
+
+// stagingFile returns the path a file is written to before it is renamed to
+// the location, in the temp directory when it is on the same file system.
+func (app *application) stagingFile(location string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(location), 0760); err != nil {
+		return "", err
+	}
+	temp, err := app.tempFile(filepath.Ext(location))
+	if err != nil {
+		return "", err
+	}
+	if sameFileSystem(filepath.Dir(temp), filepath.Dir(location)) {
+		return temp, nil
+	}
+	return partialFile(location), nil
+}
+
+// sweptDirectories holds the directories that were swept of partial files
+// in this run.
+var sweptDirectories sync.Map
+
+// partialFile returns a hidden file next to the location. The partial files
+// left in the directory by the runs that were killed or crashed are removed
+// the first time it is used.
+func partialFile(location string) string {
+	dir := filepath.Dir(location)
+	if _, swept := sweptDirectories.LoadOrStore(dir, struct{}{}); !swept {
+		sweepPartialFiles(dir)
+	}
+	return filepath.Join(dir, partialFilePrefix+uuid.New().String()+filepath.Ext(location))
+}
+
+func sweepPartialFiles(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), partialFilePrefix) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) >= staleTempAge {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// moveIntoPlace renames the finished temp file to its location. When the
+// location is on another file system, like a separate dedupe store, the file
+// is copied next to the location first and renamed from there.
+func moveIntoPlace(temp, location string) error {
+	if err := os.MkdirAll(filepath.Dir(location), 0760); err != nil {
+		return err
+	}
+	err := os.Rename(temp, location)
+	if !errors.Is(err, errCrossDevice) {
+		return err
+	}
+	staged := partialFile(location)
+	if err := copyFile(temp, staged); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	if err := os.Rename(staged, location); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	return os.Remove(temp)
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
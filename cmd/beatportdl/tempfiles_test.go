@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unspok3n/beatportdl/config"
+)
+
+func TestSweepTempDirectory(t *testing.T) {
+	s := &state{logWriter: io.Discard}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{
+		config: &config.AppConfig{DownloadsDirectory: t.TempDir()},
+		state:  s,
+	}
+
+	stale, err := app.tempFile(".flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := app.tempFile(".flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{stale, fresh} {
+		if err := os.WriteFile(path, []byte("partial"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(stale) != filepath.Join(app.config.DownloadsDirectory, tempDirectoryName) {
+		t.Errorf("temp file %s is not in the temp directory", stale)
+	}
+
+	app.sweepTempDirectory()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale temp file was not removed")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Error("fresh temp file was removed")
+	}
+}
+
+func TestStagingFile(t *testing.T) {
+	dir := t.TempDir()
+	app := &application{config: &config.AppConfig{DownloadsDirectory: dir}}
+
+	location := filepath.Join(dir, "Label", "Track.flac")
+	temp, err := app.stagingFile(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(temp) != app.tempDirectory() || filepath.Ext(temp) != ".flac" {
+		t.Errorf("stagingFile() = %s, want a file in the temp directory", temp)
+	}
+
+	stale := filepath.Join(dir, "Label", partialFilePrefix+"stale.flac")
+	fresh := filepath.Join(dir, "Label", partialFilePrefix+"fresh.flac")
+	other := filepath.Join(dir, "Label", ".other.flac")
+	for _, path := range []string{stale, fresh, other} {
+		if err := os.WriteFile(path, []byte("partial"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	for _, path := range []string{stale, other} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	partial := partialFile(location)
+	if filepath.Dir(partial) != filepath.Dir(location) || !strings.HasPrefix(filepath.Base(partial), partialFilePrefix) {
+		t.Errorf("partialFile() = %s", partial)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale partial file was not removed")
+	}
+	for _, path := range []string{fresh, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed", path)
+		}
+	}
+}
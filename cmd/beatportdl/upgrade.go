@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/taglib"
+)
+
+const (
+	upgradeArchiveDirectory = "_archive"
+)
+
+type upgradeStatus string
+
+const (
+	upgradeUpgraded    upgradeStatus = "upgraded"
+	upgradePlanned     upgradeStatus = "planned"
+	upgradeCurrent     upgradeStatus = "current"
+	upgradeUnavailable upgradeStatus = "unavailable"
+	upgradeMissing     upgradeStatus = "missing"
+	upgradeFailed      upgradeStatus = "failed"
+)
+
+type upgradeResult struct {
+	path    string
+	status  upgradeStatus
+	from    string
+	to      string
+	newPath string
+	reason  string
+}
+
+// qualityTier ranks a bitrate in kbps as medium (1), high (2) or lossless (3).
+func qualityTier(kbps int) int {
+	switch {
+	case kbps >= 500:
+		return 3
+	case kbps >= 200:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// fileQuality returns the display quality and approximate bitrate of a
+// library file, from its extension and the audio properties read by taglib.
+func fileQuality(path string) (string, int, error) {
+	if strings.EqualFold(filepath.Ext(path), ".flac") {
+		return "FLAC", 900, nil
+	}
+	file, err := taglib.Read(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	kbps := file.Bitrate()
+	return fmt.Sprintf("AAC %dkbps", kbps), kbps, nil
+}
+
+func (app *application) upgradeCommand(args []string) {
+	fs := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	storeFlag := fs.String("store", string(beatport.StoreBeatport), "Store to download the upgrades from (beatport, beatsource)")
+	archiveFlag := fs.Bool("archive", false, "Move the replaced files to the _archive folder of the directory instead of deleting them")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: beatportdl upgrade [flags] <directory>...")
+		os.Exit(exitUsage)
+	}
+
+	inst, err := app.storeInstance(beatport.Store(*storeFlag))
+	if err != nil {
+		app.FatalError("upgrade", err)
+	}
+
+	var (
+		counts = make(map[upgradeStatus]int)
+		total  int
+		mutex  sync.Mutex
+	)
+	wg := sync.WaitGroup{}
+	for _, root := range fs.Args() {
+		paths, err := audioFiles(root)
+		if err != nil {
+			app.FatalError("upgrade", err)
+		}
+		for _, path := range paths {
+			rel, _ := filepath.Rel(root, path)
+			if strings.HasPrefix(rel, upgradeArchiveDirectory+string(filepath.Separator)) {
+				continue
+			}
+			app.downloadWorker(&wg, func() {
+				result := app.upgradeFile(inst, root, path, *archiveFlag)
+				switch result.status {
+				case upgradeFailed:
+					app.failed.Add(1)
+				case upgradeUpgraded, upgradePlanned:
+					app.succeeded.Add(1)
+				}
+				if result.status != upgradeCurrent {
+					fmt.Println(result.String())
+				}
+				mutex.Lock()
+				counts[result.status]++
+				total++
+				mutex.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	upgraded := counts[upgradeUpgraded]
+	if app.config.DryRun {
+		upgraded = counts[upgradePlanned]
+	}
+	fmt.Printf(
+		"Checked %d files: %d upgraded, %d already %s or better, %d not available in better quality, %d not found, %d failed\n",
+		total, upgraded, counts[upgradeCurrent], app.config.Quality,
+		counts[upgradeUnavailable], counts[upgradeMissing], counts[upgradeFailed],
+	)
+}
+
+func (r upgradeResult) String() string {
+	s := fmt.Sprintf("[%s] %s", r.status, r.path)
+	if r.to != "" {
+		s += fmt.Sprintf(" (%s -> %s)", r.from, r.to)
+	}
+	if r.newPath != "" && r.newPath != r.path {
+		s += "\n  saved as " + r.newPath
+	}
+	if r.reason != "" {
+		s += "\n  " + r.reason
+	}
+	return s
+}
+
+func (app *application) upgradeFile(inst *beatport.Beatport, root, path string, archive bool) upgradeResult {
+	result := upgradeResult{path: path}
+	fail := func(err error) upgradeResult {
+		result.status = upgradeFailed
+		result.reason = err.Error()
+		return result
+	}
+
+	from, kbps, err := fileQuality(path)
+	if err != nil {
+		return fail(fmt.Errorf("read audio properties: %w", err))
+	}
+	result.from = from
+	_, target, targetKbps := plannedQuality(app.config.Quality)
+	if qualityTier(kbps) >= qualityTier(targetKbps) {
+		result.status = upgradeCurrent
+		return result
+	}
+
+	tags, err := app.readLibraryTags(path)
+	if err != nil {
+		return fail(fmt.Errorf("read tags: %w", err))
+	}
+	track, _, err := app.matchLibraryFile(inst, path, tags)
+	if err != nil {
+		return fail(err)
+	}
+	if track == nil {
+		result.status = upgradeMissing
+		result.reason = "no matching track found"
+		return result
+	}
+
+	if app.config.DryRun {
+		// The download location is not requested in a dry run
+		result.status = upgradePlanned
+		result.to = target
+		return result
+	}
+
+	download, err := inst.DownloadTrack(track.ID, app.config.Quality)
+	if err != nil {
+		return fail(fmt.Errorf("download track: %w", err))
+	}
+	ext, to, downloadKbps, err := streamQuality(download.StreamQuality)
+	if err != nil {
+		return fail(err)
+	}
+	if qualityTier(downloadKbps) <= qualityTier(kbps) {
+		result.status = upgradeUnavailable
+		result.reason = fmt.Sprintf("%s is the best available quality", to)
+		return result
+	}
+	result.to = to
+
+	release, err := inst.GetRelease(track.Release.ID)
+	if err != nil {
+		return fail(fmt.Errorf("fetch release: %w", err))
+	}
+	track.Release = *release
+
+	// The upgrade keeps the file name, only the extension changes
+	newPath := strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	if newPath != path {
+		if _, err := os.Stat(newPath); err == nil {
+			return fail(fmt.Errorf("%s: %w", newPath, ErrTrackFileExists))
+		}
+	}
+
+	temp, err := app.tempFile(ext)
+	if err != nil {
+		return fail(err)
+	}
+	defer os.Remove(temp)
+	if err := app.fetchVerifiedTrackFile(track, download, nil, temp, ""); err != nil {
+		return fail(err)
+	}
+	if err := app.tagLibraryFile(temp, track); err != nil {
+		return fail(fmt.Errorf("tag track: %w", err))
+	}
+	if err := app.replaceLibraryFile(root, path, newPath, temp, archive); err != nil {
+		return fail(err)
+	}
+
+	result.status = upgradeUpgraded
+	result.newPath = newPath
+	return result
+}
+
+// replaceLibraryFile moves the upgraded temp file into place and archives or
+// removes the old file. The path always holds either the old or the new file.
+func (app *application) replaceLibraryFile(root, path, newPath, temp string, archive bool) error {
+	var archivePath string
+	if archive {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		archivePath = filepath.Join(root, upgradeArchiveDirectory, rel)
+		if err := os.MkdirAll(filepath.Dir(archivePath), 0760); err != nil {
+			return fmt.Errorf("create archive directory: %w", err)
+		}
+	}
+
+	if newPath == path {
+		if archive {
+			if err := linkOrCopy(path, archivePath); err != nil {
+				return fmt.Errorf("archive old file: %w", err)
+			}
+		}
+		if err := moveIntoPlace(temp, path); err != nil {
+			return fmt.Errorf("replace file: %w", err)
+		}
+		return nil
+	}
+
+	if err := renameNoReplace(temp, newPath); err != nil {
+		return fmt.Errorf("move upgraded file: %w", err)
+	}
+	if archive {
+		if err := os.Rename(path, archivePath); err != nil {
+			return fmt.Errorf("archive old file: %w", err)
+		}
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove old file: %w", err)
+	}
+	return nil
+}
+
+func linkOrCopy(from, to string) error {
+	if err := os.Link(from, to); err == nil || errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return copyFile(from, to)
+}
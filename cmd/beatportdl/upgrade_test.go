@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceLibraryFile(t *testing.T) {
+	root := t.TempDir()
+	app := &application{}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// 128k AAC to 256k AAC keeps the path
+	same := filepath.Join(root, "Label", "Track.m4a")
+	write(same, "old")
+	write(filepath.Join(root, "Label", ".temp.m4a"), "new")
+	if err := app.replaceLibraryFile(root, same, same, filepath.Join(root, "Label", ".temp.m4a"), true); err != nil {
+		t.Fatalf("replaceLibraryFile() failed: %v", err)
+	}
+	if read(same) != "new" || read(filepath.Join(root, upgradeArchiveDirectory, "Label", "Track.m4a")) != "old" {
+		t.Error("file was not replaced and archived")
+	}
+
+	// AAC to FLAC changes the extension
+	old := filepath.Join(root, "Other.m4a")
+	flac := filepath.Join(root, "Other.flac")
+	write(old, "old")
+	write(filepath.Join(root, ".temp.flac"), "new")
+	if err := app.replaceLibraryFile(root, old, flac, filepath.Join(root, ".temp.flac"), false); err != nil {
+		t.Fatalf("replaceLibraryFile() failed: %v", err)
+	}
+	if read(flac) != "new" {
+		t.Error("upgraded file was not moved into place")
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("old file was not removed")
+	}
+
+	// A file that appeared at the new path is kept, and so is the old file
+	taken := filepath.Join(root, "Taken.m4a")
+	takenFlac := filepath.Join(root, "Taken.flac")
+	write(taken, "old")
+	write(takenFlac, "other")
+	temp := filepath.Join(root, ".beatportdl-tmp", "temp.flac")
+	write(temp, "new")
+	if err := app.replaceLibraryFile(root, taken, takenFlac, temp, false); !errors.Is(err, ErrTrackFileExists) {
+		t.Fatalf("replaceLibraryFile() = %v, want ErrTrackFileExists", err)
+	}
+	if read(takenFlac) != "other" || read(taken) != "old" {
+		t.Error("existing files were replaced")
+	}
+}
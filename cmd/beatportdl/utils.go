@@ -10,9 +10,11 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"unspok3n/beatportdl/internal/beatport"
 
 	"github.com/fatih/color"
 	"github.com/vbauerster/mpb/v8"
@@ -26,11 +28,7 @@ func (app *application) globalWorker(fn func()) {
 		app.semAcquire(app.globalSem)
 		defer app.wg.Done()
 		defer app.semRelease(app.globalSem)
-		defer func() {
-			if err := recover(); err != nil {
-				fmt.Printf(fmt.Errorf("%s", err).Error())
-			}
-		}()
+		defer app.recoverPanic()
 		fn()
 	}()
 }
@@ -49,11 +47,7 @@ func (app *application) downloadWorker(wg *sync.WaitGroup, fn func()) {
 		app.semAcquire(app.downloadSem)
 		defer app.semRelease(app.downloadSem)
 
-		defer func() {
-			if err := recover(); err != nil {
-				fmt.Printf(fmt.Errorf("%s", err).Error())
-			}
-		}()
+		defer app.recoverPanic()
 		fn()
 	}()
 }
@@ -155,24 +149,41 @@ func GetLine() string {
 	return input
 }
 
-func Pause() {
-	fmt.Println("\nPress enter to exit")
-	fmt.Scanln()
-	os.Exit(1)
+// promptLine reads a line of input, letting a shutdown signal exit right away
+// since nothing runs while waiting for it.
+func (app *application) promptLine() string {
+	app.prompting.Store(true)
+	defer app.prompting.Store(false)
+	return GetLine()
 }
 
-func (app *application) LogError(caller string, err error) {
-	message := fmt.Sprintf("%s: %s\n", caller, err.Error())
-	fmt.Fprint(app.logWriter, message)
+// interactive is false in the non-interactive mode, where BeatportDL never
+// prompts for input or waits for Enter.
+var interactive = true
 
-	if app.logFile != nil {
-		app.logFile.WriteString(message)
+func Pause() {
+	if interactive {
+		fmt.Println("\nPress enter to exit")
+		fmt.Scanln()
 	}
+	os.Exit(exitFailure)
+}
+
+// LogError logs a failure. The attributes are key-value pairs, like the
+// url, store, track_id and step of the failed item.
+func (app *application) LogError(caller string, err error, attrs ...any) {
+	app.failed.Add(1)
+	app.logger.Error(caller, append(attrs, "error", err)...)
 }
 
 func (app *application) LogInfo(info string) {
-	message := fmt.Sprintf("%s\n", info)
-	fmt.Fprint(app.logWriter, message)
+	app.logger.Info(info)
+}
+
+func (app *application) recoverPanic() {
+	if err := recover(); err != nil {
+		app.LogError("panic", fmt.Errorf("%v", err), "stack", string(debug.Stack()))
+	}
 }
 
 func (app *application) FatalError(caller string, err error) {
@@ -217,6 +228,29 @@ func FindConfigFile() (string, bool, error) {
 }
 
 func FindCacheFile() (string, bool, error) {
+	return findStateFile(cacheFilename)
+}
+
+// FindStoreCacheFile returns the token cache of the store and profile, named
+// beatportdl-credentials[-<profile>].<store>.json. Beatport on the default
+// profile keeps the original cache file name.
+func FindStoreCacheFile(profile string, store beatport.Store) (string, bool, error) {
+	if profile == "" && store == beatport.StoreBeatport {
+		return FindCacheFile()
+	}
+	ext := filepath.Ext(cacheFilename)
+	name := strings.TrimSuffix(cacheFilename, ext)
+	if profile != "" {
+		name += "-" + profile
+	}
+	return findStateFile(name + "." + string(store) + ext)
+}
+
+func FindWatchFile() (string, bool, error) {
+	return findStateFile(watchFilename)
+}
+
+func findStateFile(fileName string) (string, bool, error) {
 	var additionalDirs []string
 
 	if runtime.GOOS == "linux" {
@@ -229,7 +263,7 @@ func FindCacheFile() (string, bool, error) {
 		additionalDirs = append(additionalDirs, additionalDir)
 	}
 
-	return findFile(cacheFilename, additionalDirs)
+	return findFile(fileName, additionalDirs)
 }
 
 func FindErrorLogFile() (string, bool, error) {
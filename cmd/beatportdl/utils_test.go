@@ -4,6 +4,7 @@ import (
 	"os"
 	"path"
 	"testing"
+	"unspok3n/beatportdl/internal/beatport"
 )
 
 func TestFindConfigFile(t *testing.T) {
@@ -73,3 +74,33 @@ func TestFindCacheFile(t *testing.T) {
 		}
 	})
 }
+
+func TestFindStoreCacheFile(t *testing.T) {
+	os.Setenv("XDG_STATE_HOME", "/tmp/foo/bar")
+
+	tests := []struct {
+		profile string
+		store   beatport.Store
+		want    string
+	}{
+		{"", beatport.StoreBeatport, "beatportdl-credentials.json"},
+		{"", beatport.StoreBeatsource, "beatportdl-credentials.beatsource.json"},
+		{"beatsource", beatport.StoreBeatport, "beatportdl-credentials-beatsource.beatport.json"},
+		{"x", beatport.StoreBeatsource, "beatportdl-credentials-x.beatsource.json"},
+		{"x-beatsource", beatport.StoreBeatport, "beatportdl-credentials-x-beatsource.beatport.json"},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		cacheFilePath, _, err := FindStoreCacheFile(tt.profile, tt.store)
+		if err != nil {
+			t.Fatalf("FindStoreCacheFile() failed: %v", err)
+		}
+		if want := path.Join("/tmp/foo/bar", "beatportdl", tt.want); cacheFilePath != want {
+			t.Errorf("FindStoreCacheFile(%q, %q) = %s, want %s", tt.profile, tt.store, cacheFilePath, want)
+		}
+		if seen[cacheFilePath] {
+			t.Errorf("FindStoreCacheFile(%q, %q) = %s is shared", tt.profile, tt.store, cacheFilePath)
+		}
+		seen[cacheFilePath] = true
+	}
+}
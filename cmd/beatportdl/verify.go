@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unspok3n/beatportdl/internal/beatport"
+	"unspok3n/beatportdl/internal/verify"
+)
+
+const (
+	// verifyAttempts is how many times a track is downloaded when the file fails verification
+	verifyAttempts = 3
+	// verifyDurationTolerance is the allowed difference from the track length listed in the catalog
+	verifyDurationTolerance = 3 * time.Second
+)
+
+// verifyTrackFile checks that the downloaded file decodes and has the length
+// of the track.
+func (app *application) verifyTrackFile(path string, track *beatport.Track) error {
+	if !app.config.VerifyDownloads {
+		return nil
+	}
+	info, err := verify.File(path)
+	if err != nil {
+		return fmt.Errorf("verify file: %w", err)
+	}
+	if track.LengthMs > 0 {
+		expected := time.Duration(track.LengthMs) * time.Millisecond
+		if err := verify.Duration(info, expected, verifyDurationTolerance); err != nil {
+			return fmt.Errorf("verify file: %w", err)
+		}
+	}
+	return nil
+}
+
+type corruptFile struct {
+	path    string
+	err     error
+	trackID string
+	url     string
+}
+
+func (app *application) verifyCommand(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: beatportdl verify <directory>...")
+		os.Exit(exitUsage)
+	}
+
+	var paths []string
+	for _, dir := range fs.Args() {
+		files, err := audioFiles(dir)
+		if err != nil {
+			app.FatalError("verify", err)
+		}
+		paths = append(paths, files...)
+	}
+
+	var (
+		corrupt []corruptFile
+		mutex   sync.Mutex
+		wg      sync.WaitGroup
+	)
+	sem := make(chan struct{}, runtime.NumCPU())
+	for _, path := range paths {
+		if app.ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err := verify.File(path); err != nil {
+				file := app.corruptFile(path, err)
+				mutex.Lock()
+				corrupt = append(corrupt, file)
+				mutex.Unlock()
+				app.failed.Add(1)
+				return
+			}
+			app.succeeded.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(corrupt, func(i, j int) bool {
+		return corrupt[i].path < corrupt[j].path
+	})
+	for _, file := range corrupt {
+		fmt.Printf("%s: %v\n", file.path, file.err)
+		if file.trackID != "" {
+			fmt.Printf("  track id: %s\n", file.trackID)
+		}
+		if file.url != "" {
+			fmt.Printf("  url: %s\n", file.url)
+		}
+	}
+	fmt.Printf("Verified %d files, %d corrupt\n", len(paths), len(corrupt))
+}
+
+// corruptFile reads the Beatport track ID and URL from the tags set with
+// the track_id and track_url tag mappings, so that the track can be downloaded again.
+func (app *application) corruptFile(path string, err error) corruptFile {
+	file := corruptFile{path: path, err: err}
+	tags, tagErr := app.readLibraryTags(path)
+	if tagErr != nil {
+		return file
+	}
+	file.trackID, file.url = tags.trackID, tags.url
+	if file.trackID == "" && file.url != "" {
+		if link, err := app.bp.ParseUrl(file.url); err == nil && link.Type == beatport.TrackLink {
+			file.trackID = strconv.FormatInt(link.ID, 10)
+		}
+	}
+	if file.url == "" && file.trackID != "" {
+		if id, err := strconv.ParseInt(file.trackID, 10, 64); err == nil {
+			track := beatport.Track{ID: id, Slug: "-", Store: beatport.StoreBeatport}
+			file.url = track.StoreUrl()
+		}
+	}
+	return file
+}
+
+// audioFiles returns the FLAC and M4A files in the directory, skipping the temp directory.
+func audioFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == tempDirectoryName || d.Name() == dedupeDirectoryName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".flac", ".m4a":
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
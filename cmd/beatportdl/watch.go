@@ -0,0 +1,521 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+type watchState struct {
+	Subscriptions []*subscription `json:"subscriptions"`
+}
+
+type subscription struct {
+	URL         string            `json:"url"`
+	Store       beatport.Store    `json:"store"`
+	Type        beatport.LinkType `json:"type"`
+	ID          int64             `json:"id"`
+	Name        string            `json:"name"`
+	LastChecked time.Time         `json:"last_checked"`
+	watchBaseline
+}
+
+// watchBaseline is the newest content seen for a subscription. Labels and
+// artists are tracked by the latest date and the IDs released on that date,
+// playlists and charts by the IDs of all their tracks.
+type watchBaseline struct {
+	LastDate string  `json:"last_date,omitempty"`
+	SeenIDs  []int64 `json:"seen_ids,omitempty"`
+}
+
+// watchItem is a release of a label subscription, or a track of the other
+// subscriptions.
+type watchItem struct {
+	ID      int64
+	Date    string
+	release *beatport.Release
+	track   *beatport.Track
+}
+
+const (
+	watchOrderParams = "order_by=-publish_date"
+)
+
+var (
+	ErrUnsupportedWatchLinkType = errors.New("unsupported watch link type")
+	ErrSubscriptionExists       = errors.New("subscription already exists")
+	ErrSubscriptionNotFound     = errors.New("subscription not found")
+)
+
+func (app *application) watch(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: beatportdl watch <add|remove|list|run> [arguments]")
+		os.Exit(exitUsage)
+	}
+
+	statePath, _, err := FindWatchFile()
+	if err != nil {
+		app.FatalError("find watch file", err)
+	}
+	state, err := loadWatchState(statePath)
+	if err != nil {
+		app.FatalError("load watch state", err)
+	}
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("watch add", flag.ExitOnError)
+		allFlag := fs.Bool("all", false, "Download the full back catalogue on the first run")
+		fs.Parse(args[1:])
+		for _, url := range fs.Args() {
+			sub, err := app.subscribe(state, url, *allFlag)
+			if err != nil {
+				app.errorLogWrapper(url, "subscribe", err)
+				continue
+			}
+			app.LogInfo(fmt.Sprintf("Subscribed to %s %s", sub.Type, sub.Name))
+		}
+	case "remove":
+		for _, url := range args[1:] {
+			if err := state.remove(url); err != nil {
+				app.errorLogWrapper(url, "unsubscribe", err)
+			}
+		}
+	case "list":
+		for _, sub := range state.Subscriptions {
+			lastChecked := "never"
+			if !sub.LastChecked.IsZero() {
+				lastChecked = sub.LastChecked.Format(time.DateTime)
+			}
+			fmt.Printf("[%s] %s - %s (last checked: %s)\n", sub.Type, sub.Name, sub.URL, lastChecked)
+		}
+		return
+	case "run":
+		fs := flag.NewFlagSet("watch run", flag.ExitOnError)
+		onceFlag := fs.Bool("once", false, "Check all subscriptions once and exit")
+		intervalFlag := fs.String("interval", app.config.WatchInterval, "Time between checks")
+		fs.Parse(args[1:])
+		interval, err := time.ParseDuration(*intervalFlag)
+		if err != nil {
+			app.FatalError("parse interval", err)
+		}
+		app.watchLoop(state, statePath, interval, *onceFlag)
+		return
+	default:
+		fmt.Printf("Unknown watch command: %s\n", args[0])
+		os.Exit(exitUsage)
+	}
+
+	if err := state.save(statePath); err != nil {
+		app.FatalError("save watch state", err)
+	}
+}
+
+func (app *application) watchLoop(state *watchState, statePath string, interval time.Duration, once bool) {
+	poll := func(sub *subscription) ([]watchItem, error) {
+		return app.pollSubscription(sub, false)
+	}
+	for {
+		app.checkSubscriptions(state, statePath, poll)
+
+		if once || app.ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkSubscriptions polls the subscriptions, downloads the new items and
+// saves the baselines of the items that were handled, also when the run is
+// stopped on the way.
+func (app *application) checkSubscriptions(state *watchState, statePath string, poll func(*subscription) ([]watchItem, error)) {
+	polls := make(map[*subscription][]watchItem, len(state.Subscriptions))
+	pending := make(map[*subscription][]watchItem)
+	for _, sub := range state.Subscriptions {
+		if app.ctx.Err() != nil {
+			break
+		}
+		items, err := poll(sub)
+		if err != nil {
+			app.errorLogWrapper(sub.URL, "poll subscription", err)
+			continue
+		}
+		polls[sub] = items
+		if newItems := sub.newItems(items); len(newItems) > 0 {
+			app.infoLogWrapper(sub.URL, fmt.Sprintf("found %d new items", len(newItems)))
+			pending[sub] = newItems
+		}
+	}
+
+	done := make(map[*subscription][]int64, len(pending))
+	doneMutex := sync.Mutex{}
+	if len(pending) > 0 && app.ctx.Err() == nil {
+		app.runDownloads(func() {
+			for sub, items := range pending {
+				app.globalWorker(func() {
+					ids := app.downloadSubscription(sub, items)
+					doneMutex.Lock()
+					done[sub] = ids
+					doneMutex.Unlock()
+				})
+			}
+		})
+	}
+
+	// A dry run does not move the baselines, so the same items are found again
+	if !app.config.DryRun {
+		now := time.Now()
+		for sub, items := range polls {
+			sub.watchBaseline = sub.nextBaseline(items, done[sub])
+			sub.LastChecked = now
+		}
+	}
+	if err := state.save(statePath); err != nil {
+		app.LogError("save watch state", err)
+	}
+}
+
+func (app *application) subscribe(state *watchState, url string, all bool) (*subscription, error) {
+	link, err := app.bp.ParseUrl(url)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range state.Subscriptions {
+		if sub.Store == link.Store && sub.Type == link.Type && sub.ID == link.ID {
+			return nil, ErrSubscriptionExists
+		}
+	}
+
+	inst, err := app.storeInstance(link.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscription{
+		URL:   url,
+		Store: link.Store,
+		Type:  link.Type,
+		ID:    link.ID,
+	}
+
+	switch link.Type {
+	case beatport.LabelLink:
+		label, err := inst.GetLabel(link.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetch label: %w", err)
+		}
+		sub.Name = label.Name
+	case beatport.ArtistLink:
+		artist, err := inst.GetArtist(link.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetch artist: %w", err)
+		}
+		sub.Name = artist.Name
+	case beatport.PlaylistLink:
+		playlist, err := inst.GetPlaylist(link.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetch playlist: %w", err)
+		}
+		sub.Name = playlist.Name
+	case beatport.ChartLink:
+		chart, err := inst.GetChart(link.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetch chart: %w", err)
+		}
+		sub.Name = chart.Name
+	default:
+		return nil, ErrUnsupportedWatchLinkType
+	}
+
+	if !all {
+		items, err := app.pollSubscription(sub, true)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		sub.watchBaseline = sub.nextBaseline(items, ids)
+		sub.LastChecked = time.Now()
+	}
+
+	state.Subscriptions = append(state.Subscriptions, sub)
+	return sub, nil
+}
+
+// pollSubscription returns the items of the subscription that are compared
+// with its baseline. Labels and artists only return the items since the last
+// seen date, and with firstPage set only their latest page, which is enough to
+// set up the baseline of a new subscription.
+func (app *application) pollSubscription(sub *subscription, firstPage bool) ([]watchItem, error) {
+	inst, err := app.storeInstance(sub.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sub.Type {
+	case beatport.LabelLink:
+		return pollDated[beatport.Release](sub, firstPage, inst.GetLabelReleases, releaseWatchItem)
+	case beatport.ArtistLink:
+		return pollDated[beatport.Track](sub, firstPage, inst.GetArtistTracks, trackWatchItem)
+	case beatport.PlaylistLink:
+		return pollAll[beatport.PlaylistItem](sub, inst.GetPlaylistItems, func(item beatport.PlaylistItem, i int) watchItem {
+			item.Track.Position = item.Position
+			return watchItem{ID: item.Track.ID, track: &item.Track}
+		})
+	case beatport.ChartLink:
+		return pollAll[beatport.Track](sub, inst.GetChartTracks, func(t beatport.Track, i int) watchItem {
+			t.Position = i + 1
+			return watchItem{ID: t.ID, track: &t}
+		})
+	default:
+		return nil, ErrUnsupportedWatchLinkType
+	}
+}
+
+// The listings are ordered by the publish date, so it is also the date the
+// baseline is compared with.
+func releaseWatchItem(r beatport.Release) watchItem {
+	return watchItem{ID: r.ID, Date: r.PublishDate, release: &r}
+}
+
+func trackWatchItem(t beatport.Track) watchItem {
+	return watchItem{ID: t.ID, Date: t.PublishDate, track: &t}
+}
+
+// newItems returns the items that are not part of the baseline.
+func (sub *subscription) newItems(items []watchItem) []watchItem {
+	var newItems []watchItem
+	for _, item := range items {
+		if !slices.Contains(sub.SeenIDs, item.ID) {
+			newItems = append(newItems, item)
+		}
+	}
+	return newItems
+}
+
+// nextBaseline returns the baseline once the new items with the done IDs are
+// downloaded. The other new items stay new: labels and artists do not move
+// past the date of the oldest one, so it is found again on the next check
+// along with the newer items, which are then skipped as existing files.
+func (sub *subscription) nextBaseline(items []watchItem, done []int64) watchBaseline {
+	failed := make(map[int64]bool)
+	for _, item := range sub.newItems(items) {
+		if !slices.Contains(done, item.ID) {
+			failed[item.ID] = true
+		}
+	}
+
+	switch sub.Type {
+	case beatport.LabelLink, beatport.ArtistLink:
+		var limit string
+		for _, item := range items {
+			if failed[item.ID] && (limit == "" || item.Date < limit) {
+				limit = item.Date
+			}
+		}
+		baseline := watchBaseline{
+			LastDate: sub.LastDate,
+			SeenIDs:  slices.Clone(sub.SeenIDs),
+		}
+		for _, item := range items {
+			if failed[item.ID] || (limit != "" && item.Date > limit) {
+				continue
+			}
+			if item.Date > baseline.LastDate {
+				baseline.LastDate = item.Date
+				baseline.SeenIDs = nil
+			}
+			if item.Date == baseline.LastDate && !slices.Contains(baseline.SeenIDs, item.ID) {
+				baseline.SeenIDs = append(baseline.SeenIDs, item.ID)
+			}
+		}
+		return baseline
+	default:
+		var baseline watchBaseline
+		for _, item := range items {
+			if !failed[item.ID] {
+				baseline.SeenIDs = append(baseline.SeenIDs, item.ID)
+			}
+		}
+		return baseline
+	}
+}
+
+// downloadSubscription downloads the new items into the directory of the
+// subscribed label, artist, playlist or chart, and returns the IDs of the
+// items that were handled without errors.
+func (app *application) downloadSubscription(sub *subscription, items []watchItem) []int64 {
+	inst, err := app.storeInstance(sub.Store)
+	if err != nil {
+		app.errorLogWrapper(sub.URL, "handle subscription", err)
+		return nil
+	}
+
+	var entity DownloadsDirectoryEntity
+	var trackCount int
+	switch sub.Type {
+	case beatport.LabelLink:
+		entity, err = inst.GetLabel(sub.ID)
+	case beatport.ArtistLink:
+		entity, err = inst.GetArtist(sub.ID)
+	case beatport.PlaylistLink:
+		var playlist *beatport.Playlist
+		if playlist, err = inst.GetPlaylist(sub.ID); err == nil {
+			entity, trackCount = playlist, playlist.TrackCount
+		}
+	case beatport.ChartLink:
+		var chart *beatport.Chart
+		if chart, err = inst.GetChart(sub.ID); err == nil {
+			entity, trackCount = chart, chart.TrackCount
+		}
+	default:
+		err = ErrUnsupportedWatchLinkType
+	}
+	if err != nil {
+		app.errorLogWrapper(sub.URL, "fetch subscription", err)
+		return nil
+	}
+
+	downloadsDir, err := app.setupDownloadsDirectory(app.config.DownloadsDirectory, entity)
+	if err != nil {
+		app.errorLogWrapper(sub.URL, "setup downloads directory", err)
+		return nil
+	}
+
+	if err := app.checkJobSpace(sub.URL, downloadsDir, len(items)); err != nil {
+		app.errorLogWrapper(sub.URL, "check disk space", err)
+		return nil
+	}
+
+	var done []int64
+	doneMutex := sync.Mutex{}
+	markDone := func(id int64) {
+		doneMutex.Lock()
+		done = append(done, id)
+		doneMutex.Unlock()
+	}
+
+	wg := sync.WaitGroup{}
+	for _, item := range items {
+		switch sub.Type {
+		case beatport.LabelLink:
+			if app.handleContextRelease(inst, *item.release, downloadsDir) {
+				markDone(item.ID)
+			}
+		case beatport.ArtistLink:
+			app.downloadWorker(&wg, func() {
+				if app.handleArtistTrack(inst, *item.track, downloadsDir) {
+					markDone(item.ID)
+				}
+			})
+		default:
+			app.downloadWorker(&wg, func() {
+				item.track.PositionTotal = trackCount
+				if app.handleContextTrack(inst, item.track, downloadsDir, nil) {
+					markDone(item.ID)
+				}
+			})
+		}
+	}
+	wg.Wait()
+
+	return done
+}
+
+// pollDated pages through the newest items first and stops at the first item
+// that is older than the last seen date.
+func pollDated[T any](
+	sub *subscription,
+	firstPage bool,
+	fetchPage func(id int64, page int, params string) (*beatport.Paginated[T], error),
+	convert func(T) watchItem,
+) ([]watchItem, error) {
+	var items []watchItem
+	for page := 1; ; page++ {
+		paginated, err := fetchPage(sub.ID, page, watchOrderParams)
+		if err != nil {
+			return nil, fmt.Errorf("fetch page: %w", err)
+		}
+		for _, result := range paginated.Results {
+			item := convert(result)
+			if item.Date < sub.LastDate {
+				return items, nil
+			}
+			items = append(items, item)
+		}
+		if paginated.Next == nil || firstPage {
+			return items, nil
+		}
+	}
+}
+
+func pollAll[T any](
+	sub *subscription,
+	fetchPage func(id int64, page int, params string) (*beatport.Paginated[T], error),
+	convert func(T, int) watchItem,
+) ([]watchItem, error) {
+	var items []watchItem
+	err := ForPaginated[T](sub.ID, "", fetchPage, func(result T, i int) error {
+		items = append(items, convert(result, i))
+		return nil
+	})
+	return items, err
+}
+
+func (s *watchState) remove(url string) error {
+	for i, sub := range s.Subscriptions {
+		if sub.URL == url {
+			s.Subscriptions = append(s.Subscriptions[:i], s.Subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSubscriptionNotFound
+}
+
+func loadWatchState(path string) (*watchState, error) {
+	state := &watchState{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("decode watch state: %w", err)
+	}
+	return state, nil
+}
+
+func (s *watchState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return fmt.Errorf("encode watch state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create watch state directory: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("write watch state: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("write watch state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unspok3n/beatportdl/config"
+	"unspok3n/beatportdl/internal/beatport"
+)
+
+func TestPollDated(t *testing.T) {
+	next := "2"
+	pages := []*beatport.Paginated[beatport.Release]{
+		{
+			Next: &next,
+			Results: []beatport.Release{
+				{ID: 1, PublishDate: "2024-03-01", Date: "2024-01-01"},
+				{ID: 2, PublishDate: "2024-02-01", Date: "2024-03-01"},
+			},
+		},
+		{
+			Results: []beatport.Release{
+				{ID: 3, PublishDate: "2024-02-01", Date: "2024-03-01"},
+				{ID: 4, PublishDate: "2024-01-15", Date: "2024-03-01"},
+				{ID: 5, PublishDate: "2024-03-05", Date: "2024-03-01"},
+			},
+		},
+	}
+	fetchPage := func(id int64, page int, params string) (*beatport.Paginated[beatport.Release], error) {
+		if params != watchOrderParams {
+			t.Errorf("params = %q", params)
+		}
+		return pages[page-1], nil
+	}
+
+	sub := &subscription{Type: beatport.LabelLink, watchBaseline: watchBaseline{LastDate: "2024-02-01"}}
+	items, err := pollDated[beatport.Release](sub, false, fetchPage, releaseWatchItem)
+	if err != nil {
+		t.Fatalf("pollDated() failed: %v", err)
+	}
+	var ids []int64
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if !slices.Equal(ids, []int64{1, 2, 3}) {
+		t.Errorf("pollDated() = %v, want the items up to the first one published before the last date", ids)
+	}
+
+	items, err = pollDated[beatport.Release](sub, true, fetchPage, releaseWatchItem)
+	if err != nil {
+		t.Fatalf("pollDated() failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("pollDated() with firstPage = %d items, want 2", len(items))
+	}
+}
+
+func TestNextBaselineDated(t *testing.T) {
+	sub := &subscription{
+		Type:          beatport.ArtistLink,
+		watchBaseline: watchBaseline{LastDate: "2024-02-01", SeenIDs: []int64{3}},
+	}
+	items := []watchItem{
+		{ID: 1, Date: "2024-04-01"},
+		{ID: 2, Date: "2024-03-01"},
+		{ID: 5, Date: "2024-03-01"},
+		{ID: 4, Date: "2024-02-01"},
+		{ID: 3, Date: "2024-02-01"},
+	}
+
+	tests := []struct {
+		name string
+		done []int64
+		want watchBaseline
+	}{
+		{
+			name: "all done",
+			done: []int64{1, 2, 4, 5},
+			want: watchBaseline{LastDate: "2024-04-01", SeenIDs: []int64{1}},
+		},
+		{
+			name: "failed item stops at its date",
+			done: []int64{1, 2, 4},
+			want: watchBaseline{LastDate: "2024-03-01", SeenIDs: []int64{2}},
+		},
+		{
+			name: "failed item on the last date",
+			done: []int64{1, 2, 5},
+			want: watchBaseline{LastDate: "2024-02-01", SeenIDs: []int64{3}},
+		},
+		{
+			name: "nothing done",
+			want: watchBaseline{LastDate: "2024-02-01", SeenIDs: []int64{3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sub.nextBaseline(items, tt.done)
+			if got.LastDate != tt.want.LastDate || !slices.Equal(got.SeenIDs, tt.want.SeenIDs) {
+				t.Errorf("nextBaseline() = %+v, want %+v", got, tt.want)
+			}
+
+			// The failed items are new again
+			next := &subscription{Type: sub.Type, watchBaseline: got}
+			for _, item := range sub.newItems(items) {
+				if slices.Contains(tt.done, item.ID) {
+					continue
+				}
+				if !slices.ContainsFunc(next.newItems(items), func(i watchItem) bool { return i.ID == item.ID }) {
+					t.Errorf("failed item %d is not new with the next baseline", item.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestNextBaselineListing(t *testing.T) {
+	sub := &subscription{
+		Type:          beatport.PlaylistLink,
+		watchBaseline: watchBaseline{SeenIDs: []int64{1, 2, 9}},
+	}
+	items := []watchItem{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+
+	got := sub.nextBaseline(items, []int64{4})
+	if want := []int64{1, 2, 4}; !slices.Equal(got.SeenIDs, want) {
+		t.Errorf("nextBaseline() = %v, want %v", got.SeenIDs, want)
+	}
+	if got.LastDate != "" {
+		t.Errorf("nextBaseline() LastDate = %q", got.LastDate)
+	}
+}
+
+func TestCheckSubscriptionsSavesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &state{ctx: ctx, cancel: cancel, logWriter: io.Discard}
+	s.logger = slog.New(newConsoleHandler(s, slog.LevelInfo))
+	app := &application{config: &config.AppConfig{}, state: s}
+	statePath := filepath.Join(t.TempDir(), "beatportdl-watch.json")
+
+	playlist := &subscription{
+		URL:           "https://www.beatport.com/playlists/share/1",
+		Type:          beatport.PlaylistLink,
+		ID:            1,
+		watchBaseline: watchBaseline{SeenIDs: []int64{1, 2}},
+	}
+	label := &subscription{
+		URL:           "https://www.beatport.com/label/drumcode/2",
+		Type:          beatport.LabelLink,
+		ID:            2,
+		watchBaseline: watchBaseline{LastDate: "2024-01-01", SeenIDs: []int64{10}},
+	}
+	chart := &subscription{
+		URL:  "https://www.beatport.com/chart/chart/3",
+		Type: beatport.ChartLink,
+		ID:   3,
+	}
+	watch := &watchState{Subscriptions: []*subscription{playlist, label, chart}}
+
+	poll := func(sub *subscription) ([]watchItem, error) {
+		switch sub {
+		case playlist:
+			return []watchItem{{ID: 2}}, nil
+		case label:
+			// The shutdown signal arrives during the poll
+			cancel()
+			return []watchItem{{ID: 11, Date: "2024-02-01"}, {ID: 10, Date: "2024-01-01"}}, nil
+		default:
+			t.Errorf("%s polled after the cancel", sub.URL)
+			return nil, nil
+		}
+	}
+	app.checkSubscriptions(watch, statePath, poll)
+
+	saved, err := loadWatchState(statePath)
+	if err != nil {
+		t.Fatalf("loadWatchState() failed: %v", err)
+	}
+	if len(saved.Subscriptions) != 3 {
+		t.Fatalf("saved %d subscriptions, want 3", len(saved.Subscriptions))
+	}
+	if got := saved.Subscriptions[0]; !slices.Equal(got.SeenIDs, []int64{2}) || got.LastChecked.IsZero() {
+		t.Errorf("playlist = %+v, want the polled baseline", got)
+	}
+	if got := saved.Subscriptions[1]; got.LastDate != "2024-01-01" || !slices.Equal(got.SeenIDs, []int64{10}) {
+		t.Errorf("label = %+v, want the new release to stay new", got.watchBaseline)
+	}
+	if got := saved.Subscriptions[2]; !got.LastChecked.IsZero() {
+		t.Errorf("chart was checked after the cancel")
+	}
+}
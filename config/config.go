@@ -6,18 +6,40 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"time"
+	"unspok3n/beatportdl/internal/keyring"
 	"unspok3n/beatportdl/internal/validator"
 
 	"gopkg.in/yaml.v2"
 )
 
 type AppConfig struct {
-	Username      string `yaml:"username,omitempty"`
-	Password      string `yaml:"password,omitempty"`
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+	UsernameFile string `yaml:"username_file,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+	UseKeyring   bool   `yaml:"use_keyring,omitempty"`
+
+	Beatsource StoreCredentials `yaml:"beatsource,omitempty"`
+
+	TokenCachePassphrase     string `yaml:"token_cache_passphrase,omitempty"`
+	TokenCachePassphraseFile string `yaml:"token_cache_passphrase_file,omitempty"`
+	TokenCacheKeyring        bool   `yaml:"token_cache_keyring,omitempty"`
+
 	Quality       string `yaml:"quality,omitempty"`
 	WriteErrorLog bool   `yaml:"write_error_log,omitempty"`
 	ShowProgress  bool   `yaml:"show_progress,omitempty"`
 
+	LogLevel      string `yaml:"log_level,omitempty"`
+	LogFormat     string `yaml:"log_format,omitempty"`
+	LogFile       string `yaml:"log_file,omitempty"`
+	LogFileFormat string `yaml:"log_file_format,omitempty"`
+	LogMaxSize    int    `yaml:"log_max_size,omitempty"`
+	LogMaxBackups int    `yaml:"log_max_backups,omitempty"`
+
+	ManifestFile string `yaml:"manifest_file,omitempty"`
+	DryRun       bool   `yaml:"dry_run,omitempty"`
+
 	MaxGlobalWorkers   int `yaml:"max_global_workers,omitempty"`
 	MaxDownloadWorkers int `yaml:"max_download_workers,omitempty"`
 
@@ -27,25 +49,54 @@ type AppConfig struct {
 	ForceReleaseDirectories bool   `yaml:"force_release_directories,omitempty"`
 	TrackExists             string `yaml:"track_exists,omitempty"`
 	TrackNumberPadding      int    `yaml:"track_number_padding,omitempty"`
+	PlaylistSync            bool   `yaml:"playlist_sync,omitempty"`
+	PlaylistSyncRemoved     string `yaml:"playlist_sync_removed,omitempty"`
 
 	ReleaseDirectoryTemplate  string `yaml:"release_directory_template,omitempty"`
 	PlaylistDirectoryTemplate string `yaml:"playlist_directory_template,omitempty"`
 	ChartDirectoryTemplate    string `yaml:"chart_directory_template,omitempty"`
 	LabelDirectoryTemplate    string `yaml:"label_directory_template,omitempty"`
 	ArtistDirectoryTemplate   string `yaml:"artist_directory_template,omitempty"`
+	GenreDirectoryTemplate    string `yaml:"genre_directory_template,omitempty"`
 	TrackFileTemplate         string `yaml:"track_file_template,omitempty"`
 	WhitespaceCharacter       string `yaml:"whitespace_character,omitempty"`
 	ArtistsLimit              int    `yaml:"artists_limit,omitempty"`
 	ArtistsShortForm          string `yaml:"artists_short_form,omitempty"`
 	KeySystem                 string `yaml:"key_system,omitempty"`
+	PathProfile               string `yaml:"path_profile,omitempty"`
+	PathNormalization         string `yaml:"path_normalization,omitempty"`
+	PathASCII                 bool   `yaml:"path_ascii,omitempty"`
+
+	VerifyDownloads bool `yaml:"verify_downloads,omitempty"`
 
-	CoverSize string `yaml:"cover_size,omitempty"`
-	KeepCover bool   `yaml:"keep_cover,omitempty"`
-	FixTags   bool   `yaml:"fix_tags,omitempty"`
+	CoverSize         string `yaml:"cover_size,omitempty"`
+	CoverEmbedSize    string `yaml:"cover_embed_size,omitempty"`
+	CoverFileSize     string `yaml:"cover_file_size,omitempty"`
+	CoverFormat       string `yaml:"cover_format,omitempty"`
+	CoverEmbedMaxSize string `yaml:"cover_embed_max_size,omitempty"`
+	KeepCover         bool   `yaml:"keep_cover,omitempty"`
+	KeepContextImages bool   `yaml:"keep_context_images,omitempty"`
+	FixTags           bool   `yaml:"fix_tags,omitempty"`
 
 	TagMappings map[string]map[string]string `yaml:"tag_mappings,omitempty"`
 
+	Filters Filters `yaml:"filters,omitempty"`
+
+	Hooks Hooks `yaml:"hooks,omitempty"`
+
+	Storage Storage `yaml:"storage,omitempty"`
+
+	Dedupe Dedupe `yaml:"dedupe,omitempty"`
+
+	DiskSpace DiskSpace `yaml:"disk_space,omitempty"`
+
 	Proxy string `yaml:"proxy,omitempty"`
+
+	WatchInterval string `yaml:"watch_interval,omitempty"`
+
+	Profiles map[string]map[string]interface{} `yaml:"profiles,omitempty"`
+
+	sources map[string]Source
 }
 
 const (
@@ -60,12 +111,45 @@ var (
 		"update",
 	}
 
+	SupportedPlaylistSyncRemovedOptions = []string{
+		"keep",
+		"delete",
+		"archive",
+	}
+
+	SupportedLogLevels = []string{
+		"debug",
+		"info",
+		"warn",
+		"error",
+	}
+
+	SupportedLogFormats = []string{
+		"console",
+		"text",
+		"json",
+	}
+
 	SupportedKeySystems = []string{
 		"standard",
 		"standard-short",
 		"openkey",
 		"camelot",
 	}
+
+	SupportedPathProfiles = []string{
+		"portable",
+		"posix",
+		"windows",
+		"fat32",
+		"samba",
+	}
+
+	SupportedPathNormalizations = []string{
+		"none",
+		"nfc",
+		"nfd",
+	}
 )
 
 func FFMPEGInstalled() bool {
@@ -74,75 +158,226 @@ func FFMPEGInstalled() bool {
 }
 
 func Parse(filePath string) (*AppConfig, error) {
-	file, err := os.Open(filePath)
+	config, err := Load(filePath)
 	if err != nil {
 		return nil, err
 	}
-	config := AppConfig{
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Default returns the config with the default values.
+func Default() *AppConfig {
+	return &AppConfig{
 		Quality:                   "lossless",
 		CoverSize:                 DefaultCoverSize,
+		CoverFormat:               "jpeg",
+		CoverEmbedMaxSize:         "0",
 		TrackFileTemplate:         "{number}. {artists} - {name} ({mix_name})",
 		ReleaseDirectoryTemplate:  "[{catalog_number}] {artists} - {name}",
 		PlaylistDirectoryTemplate: "{name} [{created_date}]",
 		ChartDirectoryTemplate:    "{name} [{published_date}]",
 		LabelDirectoryTemplate:    "{name} [{updated_date}]",
 		ArtistDirectoryTemplate:   "{name}",
+		GenreDirectoryTemplate:    "{genre} - {name} [{date}]",
 		ArtistsLimit:              3,
 		ArtistsShortForm:          "VA",
 		KeySystem:                 "standard-short",
+		PathProfile:               "portable",
+		PathNormalization:         "none",
 		TrackExists:               "update",
 		TrackNumberPadding:        2,
+		PlaylistSyncRemoved:       "keep",
 		FixTags:                   true,
+		VerifyDownloads:           true,
 		ShowProgress:              true,
+		LogLevel:                  "info",
+		LogFormat:                 "console",
+		LogFileFormat:             "json",
+		LogMaxSize:                10,
+		LogMaxBackups:             3,
 		MaxGlobalWorkers:          15,
 		MaxDownloadWorkers:        15,
+		WatchInterval:             "1h",
+		Hooks: Hooks{
+			Timeout: "30s",
+		},
+		Storage: Storage{
+			Type: "local",
+		},
+		Dedupe: Dedupe{
+			Link: "hardlink",
+		},
+		DiskSpace: DiskSpace{
+			Reserve:  "500MB",
+			WhenFull: "abort",
+		},
+	}
+}
+
+// Load decodes the config file on top of the default values without validating it,
+// so that the values can be overridden before Validate is called.
+func Load(filePath string) (*AppConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
 	}
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
+	config := Default()
+	if err := yaml.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to decode config file: %w", err)
 	}
 
-	if config.Username == "" || config.Password == "" {
-		return nil, fmt.Errorf("username or password is not provided")
+	var values map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &values); err == nil {
+		config.markSources(values, "", SourceFile)
 	}
 
-	if config.Quality == "medium-hls" && !FFMPEGInstalled() {
-		return nil, errors.New("ffmpeg not found")
+	return config, nil
+}
+
+// StoreCredentials is a separate account for a store. When it is empty, the
+// top level username and password are used for the store.
+type StoreCredentials struct {
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+	UsernameFile string `yaml:"username_file,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+}
+
+// Credentials returns the username and password used for the store.
+func (c *AppConfig) Credentials(store string) (string, string) {
+	if store == "beatsource" && c.Beatsource.Username != "" {
+		return c.Beatsource.Username, c.Beatsource.Password
 	}
+	return c.Username, c.Password
+}
 
-	if config.TagMappings != nil {
-		if err = ValidateTagMappings(config.TagMappings); err != nil {
-			return nil, err
+func (c *AppConfig) Validate() error {
+	if c.Quality == "medium-hls" && !FFMPEGInstalled() {
+		return errors.New("ffmpeg not found")
+	}
+
+	if c.TagMappings != nil {
+		if err := ValidateTagMappings(c.TagMappings); err != nil {
+			return err
 		}
 
-		if _, ok := config.TagMappings["flac"]; !ok {
-			config.TagMappings["flac"] = DefaultTagMappings["flac"]
+		if _, ok := c.TagMappings["flac"]; !ok {
+			c.TagMappings["flac"] = DefaultTagMappings["flac"]
 		}
 
-		if _, ok := config.TagMappings["m4a"]; !ok {
-			config.TagMappings["m4a"] = DefaultTagMappings["m4a"]
+		if _, ok := c.TagMappings["m4a"]; !ok {
+			c.TagMappings["m4a"] = DefaultTagMappings["m4a"]
 		}
 	} else {
-		config.TagMappings = DefaultTagMappings
+		c.TagMappings = DefaultTagMappings
+	}
+
+	if !validator.PermittedValue(c.KeySystem, SupportedKeySystems...) {
+		return fmt.Errorf("invalid key system")
+	}
+
+	if !validator.PermittedValue(c.PathProfile, SupportedPathProfiles...) {
+		return fmt.Errorf("invalid path profile")
 	}
 
-	if !validator.PermittedValue(config.KeySystem, SupportedKeySystems...) {
-		return nil, fmt.Errorf("invalid key system")
+	if !validator.PermittedValue(c.PathNormalization, SupportedPathNormalizations...) {
+		return fmt.Errorf("invalid path normalization")
 	}
 
-	if config.DownloadsDirectory == "" {
-		return nil, fmt.Errorf("no downloads directory provided")
+	for _, size := range []string{c.CoverSize, c.EmbedCoverSize(), c.FileCoverSize()} {
+		if err := validateCoverSize(size); err != nil {
+			return err
+		}
+	}
+
+	if !validator.PermittedValue(c.CoverFormat, SupportedCoverFormats...) {
+		return fmt.Errorf("invalid cover format")
+	}
+
+	if _, err := ParseSize(c.CoverEmbedMaxSize); err != nil {
+		return fmt.Errorf("invalid cover embed max size")
+	}
+
+	if c.DownloadsDirectory == "" {
+		return fmt.Errorf("no downloads directory provided")
+	}
+
+	if (c.UseKeyring || c.TokenCacheKeyring) && !keyring.Supported() {
+		return fmt.Errorf("use_keyring and token_cache_keyring: %w", keyring.ErrUnsupportedOS)
+	}
+
+	if !validator.PermittedValue(c.TrackExists, SupportedTrackExistsOptions...) {
+		return fmt.Errorf("invalid track exists behavior")
+	}
+
+	if err := ValidateFilters(c.Filters); err != nil {
+		return err
+	}
+
+	if err := ValidateHooks(c.Hooks); err != nil {
+		return err
+	}
+
+	if err := ValidateStorage(c.Storage); err != nil {
+		return err
+	}
+
+	if err := ValidateDedupe(c.Dedupe); err != nil {
+		return err
+	}
+
+	if err := ValidateProfiles(c.Profiles); err != nil {
+		return err
+	}
+
+	if err := ValidateDiskSpace(c.DiskSpace); err != nil {
+		return err
 	}
 
-	if !validator.PermittedValue(config.TrackExists, SupportedTrackExistsOptions...) {
-		return nil, fmt.Errorf("invalid track exists behavior")
+	if c.Dedupe.Enabled && c.Storage.Type != "local" {
+		return fmt.Errorf("dedupe requires local storage")
 	}
 
-	if config.TrackNumberPadding > 10 || config.TrackNumberPadding < 0 {
-		return nil, fmt.Errorf("invalid track number padding")
+	if c.PlaylistSync && !c.SortByContext {
+		return fmt.Errorf("playlist sync requires sort_by_context")
 	}
 
-	return &config, nil
+	if c.PlaylistSync && c.Storage.Type != "local" {
+		return fmt.Errorf("playlist sync requires local storage")
+	}
+
+	if !validator.PermittedValue(c.PlaylistSyncRemoved, SupportedPlaylistSyncRemovedOptions...) {
+		return fmt.Errorf("invalid playlist sync removed behavior")
+	}
+
+	if c.TrackNumberPadding > 10 || c.TrackNumberPadding < 0 {
+		return fmt.Errorf("invalid track number padding")
+	}
+
+	if !validator.PermittedValue(c.LogLevel, SupportedLogLevels...) {
+		return fmt.Errorf("invalid log level")
+	}
+
+	if !validator.PermittedValue(c.LogFormat, SupportedLogFormats...) {
+		return fmt.Errorf("invalid log format")
+	}
+
+	if !validator.PermittedValue(c.LogFileFormat, "text", "json") {
+		return fmt.Errorf("invalid log file format")
+	}
+
+	if c.LogMaxSize < 0 || c.LogMaxBackups < 0 {
+		return fmt.Errorf("invalid log rotation settings")
+	}
+
+	if interval, err := time.ParseDuration(c.WatchInterval); err != nil || interval < time.Minute {
+		return fmt.Errorf("invalid watch interval")
+	}
+
+	return nil
 }
 
 func (c *AppConfig) Save(filePath string) error {
@@ -157,8 +392,23 @@ func (c *AppConfig) Save(filePath string) error {
 	}
 	defer file.Close()
 
+	// Passwords from the environment, a secret file or the keyring never end up in the file
+	saved := *c
+	if c.Source("password") != SourceFile && c.Source("password") != SourceDefault {
+		saved.Password = ""
+	}
+	if c.Source("beatsource.password") != SourceFile && c.Source("beatsource.password") != SourceDefault {
+		saved.Beatsource.Password = ""
+	}
+	if c.Source("token_cache_passphrase") != SourceFile && c.Source("token_cache_passphrase") != SourceDefault {
+		saved.TokenCachePassphrase = ""
+	}
+	if c.Source("storage.s3.secret_access_key") != SourceFile && c.Source("storage.s3.secret_access_key") != SourceDefault {
+		saved.Storage.S3.SecretAccessKey = ""
+	}
+
 	encoder := yaml.NewEncoder(file)
-	if err := encoder.Encode(&c); err != nil {
+	if err := encoder.Encode(&saved); err != nil {
 		return fmt.Errorf("encode config: %w", err)
 	}
 	return nil
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// OriginalCoverSize keeps the image at the size it was uploaded in.
+const OriginalCoverSize = "original"
+
+var SupportedCoverFormats = []string{
+	"jpeg",
+	"png",
+}
+
+var coverSizePattern = regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*$`)
+
+// EmbedCoverSize is the size of the picture embedded in the tracks.
+func (c *AppConfig) EmbedCoverSize() string {
+	if c.CoverEmbedSize != "" {
+		return c.CoverEmbedSize
+	}
+	return c.CoverSize
+}
+
+// FileCoverSize is the size of the cover, artist and label image files.
+func (c *AppConfig) FileCoverSize() string {
+	if c.CoverFileSize != "" {
+		return c.CoverFileSize
+	}
+	return c.CoverSize
+}
+
+func validateCoverSize(size string) error {
+	if size != OriginalCoverSize && !coverSizePattern.MatchString(size) {
+		return fmt.Errorf("invalid cover size '%s'", size)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"unspok3n/beatportdl/internal/validator"
+)
+
+// Dedupe keeps one copy of each track and quality in the store directory and
+// links it into the context directories.
+type Dedupe struct {
+	Enabled   bool   `yaml:"enabled,omitempty"`
+	Directory string `yaml:"directory,omitempty"`
+	Link      string `yaml:"link,omitempty"`
+}
+
+var SupportedDedupeLinks = []string{
+	"hardlink",
+	"symlink",
+	"reflink",
+}
+
+func ValidateDedupe(d Dedupe) error {
+	if !validator.PermittedValue(d.Link, SupportedDedupeLinks...) {
+		return fmt.Errorf("invalid dedupe link type")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unspok3n/beatportdl/internal/validator"
+)
+
+// DiskSpace is the free space kept on the downloads and temp file systems,
+// and what to do when a download would not fit.
+type DiskSpace struct {
+	Reserve  string `yaml:"reserve,omitempty"`
+	WhenFull string `yaml:"when_full,omitempty"`
+}
+
+var SupportedDiskFullActions = []string{
+	"abort",
+	"pause",
+}
+
+var sizeUnits = map[string]uint64{
+	"":   1,
+	"b":  1,
+	"k":  1e3,
+	"kb": 1e3,
+	"m":  1e6,
+	"mb": 1e6,
+	"g":  1e9,
+	"gb": 1e9,
+	"t":  1e12,
+	"tb": 1e12,
+}
+
+// ParseSize parses a size in bytes like "500MB" or "1.5GB". The units are
+// decimal, like the sizes shown by the app.
+func ParseSize(s string) (uint64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	number := strings.TrimRightFunc(s, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r == ' '
+	})
+	unit, ok := sizeUnits[strings.TrimSpace(s[len(number):])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit: %s", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return uint64(value * float64(unit)), nil
+}
+
+func ValidateDiskSpace(d DiskSpace) error {
+	if _, err := ParseSize(d.Reserve); err != nil {
+		return fmt.Errorf("invalid disk space reserve")
+	}
+	if !validator.PermittedValue(d.WhenFull, SupportedDiskFullActions...) {
+		return fmt.Errorf("invalid disk full behavior")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]uint64{
+		"0":      0,
+		"1024":   1024,
+		"500MB":  500e6,
+		"1.5 GB": 1.5e9,
+		"2g":     2e9,
+	}
+	for s, want := range tests {
+		if got, err := ParseSize(s); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "-1GB", "5 parsecs"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+type Filters struct {
+	ReleaseDateFrom string   `yaml:"release_date_from,omitempty"`
+	ReleaseDateTo   string   `yaml:"release_date_to,omitempty"`
+	BPMMin          int      `yaml:"bpm_min,omitempty"`
+	BPMMax          int      `yaml:"bpm_max,omitempty"`
+	Keys            []string `yaml:"keys,omitempty"`
+	CompatibleKeys  bool     `yaml:"compatible_keys,omitempty"`
+	Genres          []string `yaml:"genres,omitempty"`
+	IncludeMixNames []string `yaml:"include_mix_names,omitempty"`
+	ExcludeMixNames []string `yaml:"exclude_mix_names,omitempty"`
+	ReleaseTypes    []string `yaml:"release_types,omitempty"`
+	LengthMin       string   `yaml:"length_min,omitempty"`
+	LengthMax       string   `yaml:"length_max,omitempty"`
+	ExclusiveOnly   bool     `yaml:"exclusive_only,omitempty"`
+}
+
+func ValidateFilters(f Filters) error {
+	for _, date := range []string{f.ReleaseDateFrom, f.ReleaseDateTo} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid filter date '%s'", date)
+		}
+	}
+
+	if f.BPMMin < 0 || f.BPMMax < 0 || (f.BPMMax != 0 && f.BPMMin > f.BPMMax) {
+		return fmt.Errorf("invalid filter bpm range")
+	}
+
+	for _, length := range []string{f.LengthMin, f.LengthMax} {
+		if length == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(length); err != nil {
+			return fmt.Errorf("invalid filter length '%s'", length)
+		}
+	}
+
+	for _, pattern := range append(f.IncludeMixNames, f.ExcludeMixNames...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid filter mix name pattern '%s'", pattern)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+type Hooks struct {
+	Timeout string `yaml:"timeout,omitempty"`
+	Track   []Hook `yaml:"track,omitempty"`
+	URL     []Hook `yaml:"url,omitempty"`
+}
+
+// Hook is a command or a webhook that runs after a track is downloaded or a
+// URL is finished.
+type Hook struct {
+	Command []string          `yaml:"command,omitempty"`
+	Webhook string            `yaml:"webhook,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+func ValidateHooks(h Hooks) error {
+	if timeout, err := time.ParseDuration(h.Timeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid hook timeout")
+	}
+
+	for _, hook := range append(h.Track, h.URL...) {
+		if (len(hook.Command) == 0) == (hook.Webhook == "") {
+			return fmt.Errorf("hook requires either a command or a webhook")
+		}
+		if hook.Webhook != "" {
+			if u, err := url.Parse(hook.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("invalid hook webhook url '%s'", hook.Webhook)
+			}
+		}
+	}
+
+	return nil
+}
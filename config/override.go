@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown config key")
+)
+
+// Set overrides the value of the field with the given yaml key. Nested fields
+// are addressed with dots (filters.bpm_min), lists are comma separated.
+func (c *AppConfig) Set(key, value string) error {
+	return c.setFrom(key, value, SourceFlag)
+}
+
+func (c *AppConfig) setFrom(key, value string, source Source) error {
+	field, ok := lookupField(reflect.ValueOf(c).Elem(), strings.Split(key, "."))
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	if err := setValue(field, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	c.setSource(key, source)
+	return nil
+}
+
+// Keys returns all the keys that can be set from a single value, including
+// the nested ones.
+func Keys() []string {
+	return structKeys(reflect.TypeOf(AppConfig{}), "")
+}
+
+func structKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := prefix + yamlKey(field)
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int:
+			keys = append(keys, key)
+		case reflect.Slice:
+			if field.Type.Elem().Kind() == reflect.String {
+				keys = append(keys, key)
+			}
+		case reflect.Struct:
+			keys = append(keys, structKeys(field.Type, key+".")...)
+		}
+	}
+	return keys
+}
+
+// ScalarKeys returns the top level keys that can be set from a single value.
+func ScalarKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(AppConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		switch t.Field(i).Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int:
+			keys = append(keys, yamlKey(t.Field(i)))
+		}
+	}
+	return keys
+}
+
+// IsBoolKey reports whether the top level key holds a boolean value.
+func IsBoolKey(key string) bool {
+	field, ok := lookupField(reflect.ValueOf(&AppConfig{}).Elem(), []string{key})
+	return ok && field.Kind() == reflect.Bool
+}
+
+func yamlKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
+
+func lookupField(v reflect.Value, path []string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() || yamlKey(t.Field(i)) != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return v.Field(i), true
+		}
+		if v.Field(i).Kind() == reflect.Struct {
+			return lookupField(v.Field(i), path[1:])
+		}
+		return reflect.Value{}, false
+	}
+	return reflect.Value{}, false
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
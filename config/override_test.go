@@ -0,0 +1,36 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	c := AppConfig{}
+	values := map[string]string{
+		"quality":              "high",
+		"sort_by_context":      "true",
+		"max_download_workers": "4",
+		"filters.bpm_min":      "120",
+		"filters.keys":         "8A, 9A",
+	}
+	for key, value := range values {
+		if err := c.Set(key, value); err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+	if c.Quality != "high" || !c.SortByContext || c.MaxDownloadWorkers != 4 {
+		t.Errorf("unexpected config values: %+v", c)
+	}
+	if c.Filters.BPMMin != 120 || !slices.Equal(c.Filters.Keys, []string{"8A", "9A"}) {
+		t.Errorf("unexpected filter values: %+v", c.Filters)
+	}
+
+	if err := c.Set("unknown", "value"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Set(unknown) = %v, expected ErrUnknownKey", err)
+	}
+	if err := c.Set("max_download_workers", "many"); err == nil {
+		t.Error("Set(max_download_workers) should fail for a non integer value")
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ErrUnknownProfile = errors.New("unknown profile")
+	ErrProfileName    = errors.New("invalid profile name")
+)
+
+// Profile names are used in the token cache file names
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func ValidateProfiles(profiles map[string]map[string]interface{}) error {
+	for name := range profiles {
+		if !profileNameRegex.MatchString(name) {
+			return fmt.Errorf("%w: %q, use letters, digits, dashes and underscores", ErrProfileName, name)
+		}
+	}
+	return nil
+}
+
+// WithProfile returns a copy of the config with the values of the named profile
+// applied on top of it. An empty name returns the config as is.
+func (c *AppConfig) WithProfile(name string) (*AppConfig, error) {
+	profile := *c
+	profile.sources = maps.Clone(c.sources)
+	if c.TagMappings != nil {
+		profile.TagMappings = make(map[string]map[string]string, len(c.TagMappings))
+		for format, mappings := range c.TagMappings {
+			profile.TagMappings[format] = maps.Clone(mappings)
+		}
+	}
+	if name == "" {
+		return &profile, nil
+	}
+
+	values, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("encode profile: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("decode profile %s: %w", name, err)
+	}
+	var profileValues map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &profileValues); err == nil {
+		profile.markSources(profileValues, "", SourceProfile)
+	}
+
+	return &profile, nil
+}
+
+// Unset removes the option from the named profile, or from the top level
+// when the name is empty.
+func (c *AppConfig) Unset(profile, key string) {
+	if profile == "" {
+		if field, ok := lookupField(reflect.ValueOf(c).Elem(), strings.Split(key, ".")); ok {
+			field.SetZero()
+		}
+		return
+	}
+	values, ok := c.Profiles[profile]
+	if !ok {
+		return
+	}
+	first, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		delete(values, key)
+		return
+	}
+	unsetNested(values[first], rest)
+}
+
+func unsetNested(value interface{}, key string) {
+	values, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return
+	}
+	first, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		delete(values, key)
+		return
+	}
+	unsetNested(values[first], rest)
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unspok3n/beatportdl/internal/keyring"
+)
+
+type Source string
+
+// Config sources in the order of precedence, each one overrides the previous ones.
+// The secret files and the keyring are only used when no username or password is set.
+const (
+	SourceDefault    Source = "default"
+	SourceFile       Source = "file"
+	SourceProfile    Source = "profile"
+	SourceEnv        Source = "env"
+	SourceFlag       Source = "flag"
+	SourceSecretFile Source = "secret_file"
+	SourceKeyring    Source = "keyring"
+)
+
+const (
+	EnvPrefix      = "BEATPORTDL_"
+	KeyringService = "beatportdl"
+	// KeyringTokenCacheUser is the keyring entry of the generated token cache passphrase
+	KeyringTokenCacheUser = "token-cache"
+)
+
+// EnvName returns the environment variable name for the config key,
+// e.g. BEATPORTDL_FILTERS_BPM_MIN for filters.bpm_min.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ApplyEnv overrides the config values with the BEATPORTDL_* environment variables.
+func (c *AppConfig) ApplyEnv() error {
+	for _, key := range Keys() {
+		value, ok := os.LookupEnv(EnvName(key))
+		if !ok {
+			continue
+		}
+		if err := c.setFrom(key, value, SourceEnv); err != nil {
+			return fmt.Errorf("%s: %w", EnvName(key), err)
+		}
+	}
+	return nil
+}
+
+// ResolveCredentials reads the usernames and passwords from the secret files
+// or the OS keyring when they are not set directly.
+func (c *AppConfig) ResolveCredentials() error {
+	if err := c.resolveCredentials("", &c.Username, &c.Password, c.UsernameFile, c.PasswordFile); err != nil {
+		return err
+	}
+	bs := &c.Beatsource
+	if err := c.resolveCredentials("beatsource.", &bs.Username, &bs.Password, bs.UsernameFile, bs.PasswordFile); err != nil {
+		return fmt.Errorf("beatsource: %w", err)
+	}
+	return nil
+}
+
+func (c *AppConfig) resolveCredentials(prefix string, username, password *string, usernameFile, passwordFile string) error {
+	if *username == "" && usernameFile != "" {
+		value, err := readSecretFile(usernameFile)
+		if err != nil {
+			return fmt.Errorf("read username file: %w", err)
+		}
+		*username = value
+		c.setSource(prefix+"username", SourceSecretFile)
+	}
+
+	if *password == "" && passwordFile != "" {
+		value, err := readSecretFile(passwordFile)
+		if err != nil {
+			return fmt.Errorf("read password file: %w", err)
+		}
+		*password = value
+		c.setSource(prefix+"password", SourceSecretFile)
+	}
+
+	if *password == "" && c.UseKeyring && *username != "" {
+		value, err := keyring.Get(KeyringService, *username)
+		if err != nil {
+			return fmt.Errorf("read password from keyring: %w", err)
+		}
+		*password = value
+		c.setSource(prefix+"password", SourceKeyring)
+	}
+
+	return nil
+}
+
+// ResolveTokenCachePassphrase returns the passphrase the token caches are encrypted
+// with, or an empty string when they are not encrypted. With token_cache_keyring,
+// a random passphrase is generated and stored in the OS keyring on first use.
+func (c *AppConfig) ResolveTokenCachePassphrase() (string, error) {
+	if c.TokenCachePassphrase != "" {
+		return c.TokenCachePassphrase, nil
+	}
+	if c.TokenCachePassphraseFile != "" {
+		passphrase, err := readSecretFile(c.TokenCachePassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("read token cache passphrase file: %w", err)
+		}
+		return passphrase, nil
+	}
+	if !c.TokenCacheKeyring {
+		return "", nil
+	}
+
+	passphrase, err := keyring.Get(KeyringService, KeyringTokenCacheUser)
+	if err == nil {
+		return passphrase, nil
+	}
+	if !errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("read token cache passphrase from keyring: %w", err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	passphrase = hex.EncodeToString(key)
+	if err := keyring.Set(KeyringService, KeyringTokenCacheUser, passphrase); err != nil {
+		return "", fmt.Errorf("store token cache passphrase in keyring: %w", err)
+	}
+	return passphrase, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Source returns where the value of the config key comes from.
+func (c *AppConfig) Source(key string) Source {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// Value returns the value of the config key formatted for display.
+func (c *AppConfig) Value(key string) string {
+	field, ok := lookupField(reflect.ValueOf(c).Elem(), strings.Split(key, "."))
+	if !ok {
+		return ""
+	}
+	if field.Kind() == reflect.Slice {
+		return strings.Join(field.Interface().([]string), ",")
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+func (c *AppConfig) setSource(key string, source Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = source
+}
+
+func (c *AppConfig) markSources(values map[interface{}]interface{}, prefix string, source Source) {
+	for k, v := range values {
+		key := prefix + fmt.Sprint(k)
+		if nested, ok := v.(map[interface{}]interface{}); ok && key != "tag_mappings" {
+			c.markSources(nested, key+".", source)
+			continue
+		}
+		c.setSource(key, source)
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigSources(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yml")
+	passwordPath := filepath.Join(dir, "password")
+	if err := os.WriteFile(configPath, []byte("username: user\nquality: high\nfilters:\n  bpm_min: 120\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(passwordPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BEATPORTDL_QUALITY", "medium")
+	t.Setenv("BEATPORTDL_PASSWORD_FILE", passwordPath)
+
+	c, err := Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("downloads_directory", dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ResolveCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Password != "secret" {
+		t.Errorf("password = %q, expected the password file content", c.Password)
+	}
+	expected := map[string]Source{
+		"username":            SourceFile,
+		"filters.bpm_min":     SourceFile,
+		"quality":             SourceEnv,
+		"password_file":       SourceEnv,
+		"downloads_directory": SourceFlag,
+		"password":            SourceSecretFile,
+		"key_system":          SourceDefault,
+	}
+	for key, source := range expected {
+		if c.Source(key) != source {
+			t.Errorf("Source(%s) = %s, expected %s", key, c.Source(key), source)
+		}
+	}
+	if c.Value("quality") != "medium" {
+		t.Errorf("quality = %s, expected the environment value", c.Value("quality"))
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	c := Default()
+	c.Username = "base"
+	c.Profiles = map[string]map[string]interface{}{
+		"promo": {
+			"username":            "promo",
+			"downloads_directory": "/promo",
+			"filters":             map[interface{}]interface{}{"bpm_min": 120},
+		},
+	}
+
+	profile, err := c.WithProfile("promo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Username != "promo" || profile.DownloadsDirectory != "/promo" || profile.Filters.BPMMin != 120 {
+		t.Errorf("profile values not applied: %+v", profile)
+	}
+	if profile.Quality != c.Quality || profile.Source("username") != SourceProfile {
+		t.Errorf("unexpected profile config: quality %s, username source %s", profile.Quality, profile.Source("username"))
+	}
+	if c.Username != "base" {
+		t.Errorf("base config changed: %s", c.Username)
+	}
+
+	if _, err := c.WithProfile("unknown"); err == nil {
+		t.Error("WithProfile(unknown) should fail")
+	}
+}
+
+func TestUnset(t *testing.T) {
+	c := Default()
+	c.Password = "base"
+	c.Beatsource.Password = "bs-base"
+	c.Profiles = map[string]map[string]interface{}{
+		"promo": {
+			"password":   "promo",
+			"beatsource": map[interface{}]interface{}{"username": "bs-promo", "password": "bs-promo"},
+		},
+	}
+
+	c.Unset("promo", "beatsource.password")
+	if _, ok := c.Profiles["promo"]["beatsource"].(map[interface{}]interface{})["password"]; ok {
+		t.Error("profile beatsource.password was not removed")
+	}
+	if c.Profiles["promo"]["password"] != "promo" || c.Password != "base" || c.Beatsource.Password != "bs-base" {
+		t.Error("other passwords were removed")
+	}
+
+	c.Unset("", "password")
+	if c.Password != "" || c.Beatsource.Password != "bs-base" {
+		t.Errorf("Unset(password) = %q, %q", c.Password, c.Beatsource.Password)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	if err := ValidateProfiles(map[string]map[string]interface{}{"promo_2": nil, "x-beatsource": nil}); err != nil {
+		t.Errorf("ValidateProfiles() failed: %v", err)
+	}
+	for _, name := range []string{"../x", "a/b", "a:b", "a.b", ""} {
+		if err := ValidateProfiles(map[string]map[string]interface{}{name: nil}); !errors.Is(err, ErrProfileName) {
+			t.Errorf("ValidateProfiles(%q) = %v, want ErrProfileName", name, err)
+		}
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	c := Default()
+	c.Username, c.Password = "user", "secret"
+	if username, _ := c.Credentials("beatsource"); username != "user" {
+		t.Errorf("beatsource username = %q, expected the top level username", username)
+	}
+
+	c.Beatsource = StoreCredentials{Username: "bs-user", Password: "bs-secret"}
+	if username, password := c.Credentials("beatsource"); username != "bs-user" || password != "bs-secret" {
+		t.Errorf("beatsource credentials = %q, %q, expected the beatsource account", username, password)
+	}
+	if username, _ := c.Credentials("beatport"); username != "user" {
+		t.Errorf("beatport username = %q, expected the top level username", username)
+	}
+
+	c.Username, c.Password = "", ""
+	c.DownloadsDirectory = t.TempDir()
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected a beatsource only config to be valid: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"unspok3n/beatportdl/internal/validator"
+)
+
+// Storage is the backend the downloads are committed to. For the remote
+// backends the downloads directory is only used to stage the files.
+type Storage struct {
+	Type string      `yaml:"type,omitempty"`
+	S3   S3Storage   `yaml:"s3,omitempty"`
+	SFTP SFTPStorage `yaml:"sftp,omitempty"`
+}
+
+type S3Storage struct {
+	Endpoint        string `yaml:"endpoint,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+	Bucket          string `yaml:"bucket,omitempty"`
+	Prefix          string `yaml:"prefix,omitempty"`
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	PathStyle       bool   `yaml:"path_style,omitempty"`
+}
+
+type SFTPStorage struct {
+	Host         string `yaml:"host,omitempty"`
+	Port         int    `yaml:"port,omitempty"`
+	User         string `yaml:"user,omitempty"`
+	IdentityFile string `yaml:"identity_file,omitempty"`
+	Directory    string `yaml:"directory,omitempty"`
+}
+
+var SupportedStorageTypes = []string{
+	"local",
+	"s3",
+	"sftp",
+}
+
+func ValidateStorage(s Storage) error {
+	if !validator.PermittedValue(s.Type, SupportedStorageTypes...) {
+		return fmt.Errorf("invalid storage type")
+	}
+
+	switch s.Type {
+	case "s3":
+		if s.S3.Bucket == "" {
+			return fmt.Errorf("s3 storage requires a bucket")
+		}
+		if s.S3.AccessKeyID == "" || s.S3.SecretAccessKey == "" {
+			return fmt.Errorf("s3 storage requires an access key id and a secret access key")
+		}
+	case "sftp":
+		if s.SFTP.Host == "" {
+			return fmt.Errorf("sftp storage requires a host")
+		}
+		if s.SFTP.Port < 0 || s.SFTP.Port > 65535 {
+			return fmt.Errorf("invalid sftp port")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package artwork converts cover images and compresses them to a size limit.
+package artwork
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+)
+
+// minDimension is the width below which Fit stops scaling the image down.
+const minDimension = 200
+
+var ErrTooLarge = errors.New("image does not fit the size limit")
+
+// Format returns the format of the image data, or an empty string when it
+// is neither JPEG nor PNG.
+func Format(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return JPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG
+	default:
+		return ""
+	}
+}
+
+func MimeType(format string) string {
+	if format == PNG {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+func Extension(format string) string {
+	if format == PNG {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// Convert encodes the image in the format. Data already in the format is
+// returned unchanged.
+func Convert(data []byte, format string) ([]byte, error) {
+	if Format(data) == format {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return encode(img, format, jpeg.DefaultQuality)
+}
+
+// Fit compresses the image as JPEG until it takes at most maxBytes, first
+// lowering the quality and then the dimensions.
+func Fit(data []byte, maxBytes int) ([]byte, error) {
+	if len(data) <= maxBytes {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for quality := 90; quality >= 50; quality -= 10 {
+			out, err := encode(img, JPEG, quality)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) <= maxBytes {
+				return out, nil
+			}
+		}
+		bounds := img.Bounds()
+		width, height := bounds.Dx()*3/4, bounds.Dy()*3/4
+		if width < minDimension {
+			return nil, ErrTooLarge
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+		img = scaled
+	}
+}
+
+func encode(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == PNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package artwork
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func testImage(t *testing.T, size int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	r := rand.New(rand.NewSource(1))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(r.Intn(256)), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestConvert(t *testing.T) {
+	data := testImage(t, 64)
+	if got, err := Convert(data, PNG); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Convert() to the same format changed the data, error %v", err)
+	}
+	got, err := Convert(data, JPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Format(got) != JPEG {
+		t.Errorf("Convert() format = %q, want jpeg", Format(got))
+	}
+}
+
+func TestFit(t *testing.T) {
+	data := testImage(t, 800)
+	for _, limit := range []int{len(data), 100_000, 20_000} {
+		got, err := Fit(data, limit)
+		if err != nil {
+			t.Fatalf("Fit(%d) error: %v", limit, err)
+		}
+		if len(got) > limit {
+			t.Errorf("Fit(%d) = %d bytes", limit, len(got))
+		}
+	}
+	if _, err := Fit(data, 100); err != ErrTooLarge {
+		t.Errorf("Fit(100) error = %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,31 @@
+package beatport
+
+import (
+	"encoding/json"
+)
+
+type Account struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func (b *Beatport) GetAccount() (*Account, error) {
+	res, err := b.fetch(
+		"GET",
+		"/my/account/",
+		nil,
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &Account{}
+	if err = json.NewDecoder(res.Body).Decode(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -8,9 +8,11 @@ import (
 )
 
 type Artist struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
-	Slug string `json:"slug"`
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Image Image  `json:"image"`
+	Store Store  `json:"store"`
 }
 
 type Artists []Artist
@@ -18,11 +20,15 @@ type Artists []Artist
 func (a *Artist) DirectoryName(n NamingPreferences) string {
 	templateValues := map[string]string{
 		"id":   strconv.Itoa(int(a.ID)),
-		"name": SanitizeForPath(a.Name),
+		"name": n.Path.SanitizeForPath(a.Name),
 		"slug": a.Slug,
 	}
 	directoryName := ParseTemplate(n.Template, templateValues)
-	return SanitizePath(directoryName, n.Whitespace)
+	return n.Path.SanitizePath(directoryName, n.Whitespace)
+}
+
+func (a *Artist) StoreUrl() string {
+	return storeUrl(a.ID, "artist", a.Slug, a.Store)
 }
 
 func (a *Artists) Display(limit int, shortForm string) string {
@@ -52,6 +58,7 @@ func (b *Beatport) GetArtist(id int64) (*Artist, error) {
 	if err = json.NewDecoder(res.Body).Decode(response); err != nil {
 		return nil, err
 	}
+	response.Store = b.store
 	return response, nil
 }
 
@@ -9,18 +9,21 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	clientId      = "ryZ8LuyQVPqbK2mBX2Hwt4qSMtnWuTYSqBPO92yQ"
-	tokenEndpoint = "/auth/o/token/"
-	authEndpoint  = "/auth/o/authorize/?client_id=" + clientId + "&response_type=code"
-	loginEndpoint = "/auth/login/"
+	clientId       = "ryZ8LuyQVPqbK2mBX2Hwt4qSMtnWuTYSqBPO92yQ"
+	tokenEndpoint  = "/auth/o/token/"
+	authEndpoint   = "/auth/o/authorize/?client_id=" + clientId + "&response_type=code"
+	loginEndpoint  = "/auth/login/"
+	revokeEndpoint = "/auth/o/revoke_token/"
 )
 
 var (
+	ErrMissingCredentials       = errors.New("no account configured")
 	ErrInvalidAuthorizationCode = errors.New("invalid authorization code")
 	ErrInvalidSessionCookie     = errors.New("invalid session cookie")
 	ErrLoginIDMismatch          = errors.New("login id does not match")
@@ -31,6 +34,8 @@ type Auth struct {
 	password  string
 	tokenPair *tokenPair
 	cacheFile string
+	cacheKey  string
+	logger    func(message string)
 	mutex     sync.RWMutex
 }
 
@@ -49,15 +54,39 @@ func NewAuth(username, password, cacheFile string) *Auth {
 		username:  username,
 		password:  password,
 		cacheFile: cacheFile,
+		logger: func(message string) {
+			fmt.Println(message)
+		},
 	}
 }
 
+// SetCacheKey enables the encryption of the token cache with a key derived
+// from the passphrase. A plain cache is encrypted when it is loaded.
+func (a *Auth) SetCacheKey(passphrase string) {
+	a.cacheKey = passphrase
+}
+
+// SetLogger sets the function that receives the login and token refresh events.
+func (a *Auth) SetLogger(logger func(message string)) {
+	a.logger = logger
+}
+
 func (a *Auth) LoadCache() error {
 	data, err := os.ReadFile(a.cacheFile)
 	if err != nil {
 		return fmt.Errorf("failed to read token file: %w", err)
 	}
 
+	cache, encrypted := parseEncryptedCache(data)
+	if encrypted {
+		if a.cacheKey == "" {
+			return ErrCacheEncrypted
+		}
+		if data, err = cache.decrypt(a.cacheKey); err != nil {
+			return err
+		}
+	}
+
 	var loadedToken tokenPair
 	if err := json.Unmarshal(data, &loadedToken); err != nil {
 		return fmt.Errorf("failed to unmarshal token data: %w", err)
@@ -69,6 +98,13 @@ func (a *Auth) LoadCache() error {
 
 	a.tokenPair = &loadedToken
 
+	// Migrate a plain cache once a cache passphrase is set
+	if !encrypted && a.cacheKey != "" {
+		if err := a.WriteCache(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -78,6 +114,12 @@ func (a *Auth) WriteCache() error {
 		return fmt.Errorf("failed to marshal tokenPair: %w", err)
 	}
 
+	if a.cacheKey != "" {
+		if data, err = encryptCache(data, a.cacheKey); err != nil {
+			return fmt.Errorf("failed to encrypt token cache: %w", err)
+		}
+	}
+
 	err = os.MkdirAll(path.Dir(a.cacheFile), 0700)
 	if err != nil {
 		return fmt.Errorf("could not create folder for cache file: %w", err)
@@ -91,32 +133,60 @@ func (a *Auth) WriteCache() error {
 }
 
 func (a *Auth) Check(inst *Beatport) error {
-	currentTime := time.Now().Unix()
-	a.mutex.RLock()
-	tokenExpirationTime := a.tokenPair.IssuedAt + a.tokenPair.ExpiresIn
-	a.mutex.RUnlock()
-	if currentTime+300 >= tokenExpirationTime {
-		a.mutex.Lock()
-		fmt.Println("Refreshing token")
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.tokenPair == nil {
+		// Log in lazily, only for the stores that are actually used
+		if err := a.LoadCache(); err != nil {
+			if errors.Is(err, ErrCacheEncrypted) || errors.Is(err, ErrCacheKey) {
+				return err
+			}
+			if err := a.Init(inst); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if time.Now().Unix()+300 >= a.tokenPair.IssuedAt+a.tokenPair.ExpiresIn {
+		a.logger(fmt.Sprintf("Refreshing %s token", inst.store))
 		if _, err := a.refresh(inst); err != nil {
+			a.logger(fmt.Sprintf("Refreshing %s token failed, logging in again: %v", inst.store, err))
 			if err = a.Init(inst); err != nil {
-				a.mutex.Unlock()
 				return fmt.Errorf("invalid token and authorization error: %w", err)
 			}
 		}
-		a.mutex.Unlock()
 	}
 	return nil
 }
 
 func (a *Auth) Invalidate() {
 	a.mutex.Lock()
-	a.tokenPair.IssuedAt = 0
+	if a.tokenPair != nil {
+		a.tokenPair.IssuedAt = 0
+	}
 	a.mutex.Unlock()
 }
 
+func (a *Auth) HasCredentials() bool {
+	return a.username != "" && a.password != ""
+}
+
+func (a *Auth) accessToken() string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.tokenPair == nil {
+		return ""
+	}
+	return a.tokenPair.AccessToken
+}
+
 func (a *Auth) Init(inst *Beatport) error {
-	fmt.Println("Logging in")
+	if !a.HasCredentials() {
+		return fmt.Errorf("%w for %s, set a username and password or log in with an authorization code", ErrMissingCredentials, inst.store)
+	}
+	a.logger(fmt.Sprintf("Logging in to %s", inst.store))
 	sessionId, err := a.login(inst)
 	if err != nil {
 		return fmt.Errorf("login: %v", err)
@@ -131,6 +201,103 @@ func (a *Auth) Init(inst *Beatport) error {
 	return nil
 }
 
+// Login logs in with the username and password, replacing the cached token.
+func (a *Auth) Login(inst *Beatport) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.Init(inst)
+}
+
+// LoginWithCode issues a token from an authorization code, so that the
+// password does not have to be stored.
+func (a *Auth) LoginWithCode(inst *Beatport, code string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.issue(inst, code); err != nil {
+		return fmt.Errorf("issue token: %w", err)
+	}
+	return nil
+}
+
+// LoginWithRefreshToken issues a new token pair from a refresh token.
+func (a *Auth) LoginWithRefreshToken(inst *Beatport, refreshToken string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.tokenPair = &tokenPair{
+		RefreshToken: refreshToken,
+		LoginID:      a.loginId(),
+	}
+	if _, err := a.refresh(inst); err != nil {
+		a.tokenPair = nil
+		return fmt.Errorf("refresh token: %w", err)
+	}
+	return nil
+}
+
+// Logout revokes the cached tokens and deletes the cache file.
+func (a *Auth) Logout(inst *Beatport) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.tokenPair == nil {
+		if err := a.LoadCache(); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+		}
+	}
+
+	if a.tokenPair != nil {
+		for _, token := range []string{a.tokenPair.RefreshToken, a.tokenPair.AccessToken} {
+			if token == "" {
+				continue
+			}
+			payload := map[string]string{
+				"client_id": clientId,
+				"token":     token,
+			}
+			res, err := inst.fetch("POST", revokeEndpoint, payload, "application/x-www-form-urlencoded")
+			if err != nil {
+				return fmt.Errorf("revoke token: %w", err)
+			}
+			res.Body.Close()
+		}
+		a.tokenPair = nil
+	}
+
+	if err := os.Remove(a.cacheFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete token cache: %w", err)
+	}
+	return nil
+}
+
+// TokenExpiry returns when the current access token expires.
+func (a *Auth) TokenExpiry() (time.Time, bool) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.tokenPair == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(a.tokenPair.IssuedAt+a.tokenPair.ExpiresIn, 0), true
+}
+
+// ParseAuthorizationCode returns the code from a pasted authorization code or
+// from the URL the browser was redirected to after logging in.
+func ParseAuthorizationCode(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if strings.Contains(input, "code=") {
+		parsedUrl, err := url.Parse(input)
+		if err != nil {
+			return "", err
+		}
+		input = parsedUrl.Query().Get("code")
+	}
+	if input == "" || strings.ContainsAny(input, " /?&") {
+		return "", ErrInvalidAuthorizationCode
+	}
+	return input, nil
+}
+
 func (a *Auth) refresh(inst *Beatport) (*tokenPair, error) {
 	payload := map[string]string{
 		"client_id":     clientId,
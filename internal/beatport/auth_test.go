@@ -0,0 +1,26 @@
+package beatport
+
+import "testing"
+
+func TestParseAuthorizationCode(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{"AbC123", "AbC123"},
+		{" AbC123\n", "AbC123"},
+		{"https://api.beatport.com/v4/auth/o/post-message/?code=AbC123", "AbC123"},
+	}
+	for _, tt := range tests {
+		code, err := ParseAuthorizationCode(tt.input)
+		if err != nil || code != tt.code {
+			t.Errorf("ParseAuthorizationCode(%q) = %q, %v, expected %q", tt.input, code, err, tt.code)
+		}
+	}
+
+	for _, input := range []string{"", "https://api.beatport.com/v4/auth/o/post-message/?error=denied"} {
+		if _, err := ParseAuthorizationCode(input); err == nil {
+			t.Errorf("ParseAuthorizationCode(%q) should fail", input)
+		}
+	}
+}
@@ -64,10 +64,48 @@ func New(store Store, proxyUrl string, auth *Auth) *Beatport {
 	return &f
 }
 
+func (b *Beatport) Store() Store {
+	return b.store
+}
+
+// Login logs in to the store with the credentials of its account.
+func (b *Beatport) Login() error {
+	return b.auth.Login(b)
+}
+
+func (b *Beatport) HasCredentials() bool {
+	return b.auth.HasCredentials()
+}
+
+func (b *Beatport) Auth() *Auth {
+	return b.auth
+}
+
+// AuthorizeUrl returns the URL that shows the authorization code after
+// logging in with a browser.
+func (b *Beatport) AuthorizeUrl() string {
+	if b.store == StoreBeatsource {
+		return beatsourceBaseUrl + authEndpoint
+	}
+	return beatportBaseUrl + authEndpoint
+}
+
+func isAuthEndpoint(endpoint string) bool {
+	switch endpoint {
+	case tokenEndpoint, authEndpoint, loginEndpoint, revokeEndpoint:
+		return true
+	}
+	return false
+}
+
 func (b *Beatport) fetch(method, endpoint string, payload interface{}, contentType string) (*http.Response, error) {
+	return b.fetchWithRetry(method, endpoint, payload, contentType, true)
+}
+
+func (b *Beatport) fetchWithRetry(method, endpoint string, payload interface{}, contentType string, retry bool) (*http.Response, error) {
 	var body bytes.Buffer
 
-	if endpoint != tokenEndpoint && endpoint != authEndpoint && endpoint != loginEndpoint {
+	if !isAuthEndpoint(endpoint) {
 		if err := b.auth.Check(b); err != nil {
 			return nil, err
 		}
@@ -111,8 +149,11 @@ func (b *Beatport) fetch(method, endpoint string, payload interface{}, contentTy
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	if b.auth.tokenPair != nil {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", b.auth.tokenPair.AccessToken))
+	// The auth endpoints are called while the auth mutex is held
+	if !isAuthEndpoint(endpoint) {
+		if accessToken := b.auth.accessToken(); accessToken != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+		}
 	}
 
 	resp, err := b.client.Do(req)
@@ -121,9 +162,11 @@ func (b *Beatport) fetch(method, endpoint string, payload interface{}, contentTy
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusFound {
-		if resp.StatusCode == http.StatusUnauthorized && endpoint != tokenEndpoint && endpoint != authEndpoint && endpoint != loginEndpoint {
+		if retry && resp.StatusCode == http.StatusUnauthorized && !isAuthEndpoint(endpoint) {
+			// Retry once with a fresh token, a second 401 means that the account has no access
+			resp.Body.Close()
 			b.auth.Invalidate()
-			return b.fetch(method, endpoint, payload, contentType)
+			return b.fetchWithRetry(method, endpoint, payload, contentType, false)
 		}
 		defer resp.Body.Close()
 		response := &FetcherError{}
@@ -0,0 +1,91 @@
+package beatport
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	cacheVersion = 1
+	cacheKDF     = "scrypt"
+)
+
+var (
+	ErrCacheEncrypted = errors.New("token cache is encrypted, but no cache passphrase is set")
+	ErrCacheKey       = errors.New("token cache cannot be decrypted, the cache passphrase is wrong")
+)
+
+// encryptedCache is the format of the token cache when a cache passphrase is
+// set. The plain format is the JSON encoded token pair.
+type encryptedCache struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func parseEncryptedCache(data []byte) (*encryptedCache, bool) {
+	var cache encryptedCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Ciphertext == nil {
+		return nil, false
+	}
+	return &cache, true
+}
+
+func encryptCache(data []byte, passphrase string) ([]byte, error) {
+	cache := &encryptedCache{
+		Version: cacheVersion,
+		KDF:     cacheKDF,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(cache.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := cacheCipher(passphrase, cache.Salt)
+	if err != nil {
+		return nil, err
+	}
+	cache.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(cache.Nonce); err != nil {
+		return nil, err
+	}
+	cache.Ciphertext = aead.Seal(nil, cache.Nonce, data, nil)
+	return json.MarshalIndent(cache, "", " ")
+}
+
+func (c *encryptedCache) decrypt(passphrase string) ([]byte, error) {
+	if c.Version != cacheVersion || c.KDF != cacheKDF {
+		return nil, fmt.Errorf("unsupported token cache format: version %d, %s", c.Version, c.KDF)
+	}
+	aead, err := cacheCipher(passphrase, c.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Nonce) != aead.NonceSize() {
+		return nil, ErrCacheKey
+	}
+	data, err := aead.Open(nil, c.Nonce, c.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrCacheKey
+	}
+	return data, nil
+}
+
+func cacheCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive cache key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,51 @@
+package beatport
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedCache(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "credentials.json")
+	plain := NewAuth("user", "secret", cacheFile)
+	plain.tokenPair = &tokenPair{AccessToken: "access", RefreshToken: "refresh", LoginID: plain.loginId()}
+	if err := plain.WriteCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain cache is encrypted when it is loaded with a passphrase
+	auth := NewAuth("user", "secret", cacheFile)
+	auth.SetCacheKey("passphrase")
+	if err := auth.LoadCache(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, encrypted := parseEncryptedCache(data); !encrypted {
+		t.Fatal("expected the cache to be migrated to the encrypted format")
+	}
+
+	auth = NewAuth("user", "secret", cacheFile)
+	auth.SetCacheKey("passphrase")
+	if err := auth.LoadCache(); err != nil {
+		t.Fatal(err)
+	}
+	if auth.tokenPair.RefreshToken != "refresh" {
+		t.Errorf("refresh token = %q, expected refresh", auth.tokenPair.RefreshToken)
+	}
+
+	auth = NewAuth("user", "secret", cacheFile)
+	auth.SetCacheKey("wrong")
+	if err := auth.LoadCache(); !errors.Is(err, ErrCacheKey) {
+		t.Errorf("expected ErrCacheKey for a wrong passphrase, got %v", err)
+	}
+
+	auth = NewAuth("user", "secret", cacheFile)
+	if err := auth.LoadCache(); !errors.Is(err, ErrCacheEncrypted) {
+		t.Errorf("expected ErrCacheEncrypted without a passphrase, got %v", err)
+	}
+}
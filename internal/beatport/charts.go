@@ -18,6 +18,7 @@ type Chart struct {
 	ChangeDate  time.Time   `json:"change_date"`
 	PublishDate time.Time   `json:"publish_date"`
 	Image       Image       `json:"image"`
+	Store       Store       `json:"store"`
 }
 
 type ChartPerson struct {
@@ -32,17 +33,21 @@ func (c *Chart) DirectoryName(n NamingPreferences) string {
 	}
 	templateValues := map[string]string{
 		"id":             strconv.Itoa(int(c.ID)),
-		"name":           SanitizeForPath(c.Name),
+		"name":           n.Path.SanitizeForPath(c.Name),
 		"slug":           c.Slug,
-		"first_genre":    SanitizeForPath(firstGenre),
+		"first_genre":    n.Path.SanitizeForPath(firstGenre),
 		"track_count":    NumberWithPadding(c.TrackCount, c.TrackCount, n.TrackNumberPadding),
-		"creator":        SanitizeForPath(c.Person.OwnerName),
+		"creator":        n.Path.SanitizeForPath(c.Person.OwnerName),
 		"created_date":   c.AddDate.Format("2006-01-02"),
 		"published_date": c.PublishDate.Format("2006-01-02"),
 		"updated_date":   c.ChangeDate.Format("2006-01-02"),
 	}
 	directoryName := ParseTemplate(n.Template, templateValues)
-	return SanitizePath(directoryName, n.Whitespace)
+	return n.Path.SanitizePath(directoryName, n.Whitespace)
+}
+
+func (c *Chart) StoreUrl() string {
+	return storeUrl(c.ID, "chart", c.Slug, c.Store)
 }
 
 func (b *Beatport) GetChart(id int64) (*Chart, error) {
@@ -60,6 +65,7 @@ func (b *Beatport) GetChart(id int64) (*Chart, error) {
 	if err = json.NewDecoder(res.Body).Decode(response); err != nil {
 		return nil, err
 	}
+	response.Store = b.store
 	return response, nil
 }
 
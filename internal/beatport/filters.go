@@ -0,0 +1,247 @@
+package beatport
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type TrackFilter struct {
+	DateFrom        string
+	DateTo          string
+	BPMMin          int
+	BPMMax          int
+	Keys            []string
+	Genres          []string
+	IncludeMixNames []*regexp.Regexp
+	ExcludeMixNames []*regexp.Regexp
+	ReleaseTypes    []string
+	LengthMin       Duration
+	LengthMax       Duration
+	ExclusiveOnly   bool
+}
+
+var (
+	ErrInvalidKey = errors.New("invalid key")
+
+	camelotKeyRegex = regexp.MustCompile(`^(\d{1,2})([ABab])$`)
+	openKeyRegex    = regexp.MustCompile(`^(\d{1,2})([mdMD])$`)
+
+	standardKeys = map[string]string{
+		"abm": "1A", "g#m": "1A", "ebm": "2A", "d#m": "2A", "bbm": "3A", "a#m": "3A",
+		"fm": "4A", "cm": "5A", "gm": "6A", "dm": "7A", "am": "8A", "em": "9A", "bm": "10A",
+		"f#m": "11A", "gbm": "11A", "c#m": "12A", "dbm": "12A",
+		"b": "1B", "cb": "1B", "f#": "2B", "gb": "2B", "db": "3B", "c#": "3B", "ab": "4B", "g#": "4B",
+		"eb": "5B", "d#": "5B", "bb": "6B", "a#": "6B", "f": "7B", "c": "8B", "g": "9B",
+		"d": "10B", "a": "11B", "e": "12B",
+	}
+)
+
+// ParseKey converts a key in Camelot (8A), Open Key (1m) or standard (Am, F# Major)
+// notation to its Camelot code.
+func ParseKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+
+	if m := camelotKeyRegex.FindStringSubmatch(key); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		if number < 1 || number > 12 {
+			return "", ErrInvalidKey
+		}
+		return strconv.Itoa(number) + strings.ToUpper(m[2]), nil
+	}
+
+	if m := openKeyRegex.FindStringSubmatch(key); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		if number < 1 || number > 12 {
+			return "", ErrInvalidKey
+		}
+		if number <= 5 {
+			number += 7
+		} else {
+			number -= 5
+		}
+		letter := "A"
+		if strings.ToLower(m[2]) == "d" {
+			letter = "B"
+		}
+		return strconv.Itoa(number) + letter, nil
+	}
+
+	r := strings.NewReplacer(" ", "", "minor", "m", "major", "", "♯", "#", "♭", "b")
+	normalized := r.Replace(strings.ToLower(key))
+	if code, ok := standardKeys[normalized]; ok {
+		return code, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+}
+
+// CompatibleKeys returns the Camelot codes that mix harmonically with the given one:
+// the key itself, its relative major/minor and its neighbours on the wheel.
+func CompatibleKeys(code string) []string {
+	m := camelotKeyRegex.FindStringSubmatch(code)
+	if m == nil {
+		return nil
+	}
+	number, _ := strconv.Atoi(m[1])
+	letter := strings.ToUpper(m[2])
+	relative := "A"
+	if letter == "A" {
+		relative = "B"
+	}
+	lower := (number+10)%12 + 1
+	higher := number%12 + 1
+	return []string{
+		code,
+		strconv.Itoa(number) + relative,
+		strconv.Itoa(lower) + letter,
+		strconv.Itoa(higher) + letter,
+	}
+}
+
+// MatchRelease reports whether the release passes the filters. The date window
+// is compared with the publish date, the same field the listing requests are
+// filtered and ordered by.
+func (f *TrackFilter) MatchRelease(r *Release) bool {
+	if r.PublishDate != "" {
+		if f.DateFrom != "" && r.PublishDate < f.DateFrom {
+			return false
+		}
+		if f.DateTo != "" && r.PublishDate > f.DateTo {
+			return false
+		}
+	}
+
+	if len(f.ReleaseTypes) > 0 && r.Type.Name != "" && !containsFold(f.ReleaseTypes, r.Type.Name) {
+		return false
+	}
+
+	return true
+}
+
+func (f *TrackFilter) Match(t *Track) bool {
+	if t.Release.PublishDate == "" && t.PublishDate != "" {
+		if f.DateFrom != "" && t.PublishDate < f.DateFrom {
+			return false
+		}
+		if f.DateTo != "" && t.PublishDate > f.DateTo {
+			return false
+		}
+	}
+
+	if f.BPMMin > 0 && t.BPM < f.BPMMin {
+		return false
+	}
+	if f.BPMMax > 0 && t.BPM > f.BPMMax {
+		return false
+	}
+
+	if len(f.Keys) > 0 && !slices.Contains(f.Keys, t.Key.Camelot()) {
+		return false
+	}
+
+	if len(f.Genres) > 0 {
+		genres := []string{t.Genre.Name, t.Genre.Slug}
+		if t.Subgenre != nil {
+			genres = append(genres, t.Subgenre.Name, t.Subgenre.Slug)
+		}
+		matched := false
+		for _, genre := range genres {
+			if genre != "" && containsFold(f.Genres, genre) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	mixName := t.MixName.String()
+	if len(f.IncludeMixNames) > 0 && !matchAny(f.IncludeMixNames, mixName) {
+		return false
+	}
+	if matchAny(f.ExcludeMixNames, mixName) {
+		return false
+	}
+
+	if !f.MatchRelease(&t.Release) {
+		return false
+	}
+
+	if f.LengthMin > 0 && t.LengthMs < f.LengthMin {
+		return false
+	}
+	if f.LengthMax > 0 && t.LengthMs > f.LengthMax {
+		return false
+	}
+
+	if f.ExclusiveOnly && !t.Exclusive {
+		return false
+	}
+
+	return true
+}
+
+// TrackParams returns the query parameters for the filters that the track
+// listing endpoints can apply on the server side.
+func (f *TrackFilter) TrackParams() string {
+	values := url.Values{}
+	if f.BPMMin > 0 || f.BPMMax > 0 {
+		bpmMax := f.BPMMax
+		if bpmMax == 0 {
+			bpmMax = 999
+		}
+		values.Set("bpm", fmt.Sprintf("%d:%d", f.BPMMin, bpmMax))
+	}
+	if dateRange := f.dateRange(); dateRange != "" {
+		values.Set("publish_date", dateRange)
+	}
+	return values.Encode()
+}
+
+// ReleaseParams returns the query parameters for the filters that the release
+// listing endpoints can apply on the server side.
+func (f *TrackFilter) ReleaseParams() string {
+	values := url.Values{}
+	if dateRange := f.dateRange(); dateRange != "" {
+		values.Set("publish_date", dateRange)
+	}
+	return values.Encode()
+}
+
+func (f *TrackFilter) dateRange() string {
+	if f.DateFrom == "" && f.DateTo == "" {
+		return ""
+	}
+	from, to := f.DateFrom, f.DateTo
+	if from == "" {
+		from = "1900-01-01"
+	}
+	if to == "" {
+		to = "2999-12-31"
+	}
+	return from + ":" + to
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package beatport
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := map[string]string{
+		"8A":       "8A",
+		"12b":      "12B",
+		"1m":       "8A",
+		"6d":       "1B",
+		"Am":       "8A",
+		"F# Minor": "11A",
+		"Eb":       "5B",
+		"C Major":  "8B",
+		"Dbm":      "12A",
+	}
+	for input, expected := range tests {
+		code, err := ParseKey(input)
+		if err != nil {
+			t.Errorf("ParseKey(%q) failed: %v", input, err)
+			continue
+		}
+		if code != expected {
+			t.Errorf("ParseKey(%q) = %s, expected %s", input, code, expected)
+		}
+	}
+
+	for _, input := range []string{"13A", "H", "8C", ""} {
+		if _, err := ParseKey(input); err == nil {
+			t.Errorf("ParseKey(%q) should fail", input)
+		}
+	}
+}
+
+func TestCompatibleKeys(t *testing.T) {
+	keys := CompatibleKeys("12A")
+	for _, expected := range []string{"12A", "12B", "11A", "1A"} {
+		if !slices.Contains(keys, expected) {
+			t.Errorf("CompatibleKeys(12A) = %v, missing %s", keys, expected)
+		}
+	}
+}
+
+func TestTrackFilterMatch(t *testing.T) {
+	track := Track{
+		BPM:      126,
+		MixName:  "Extended Mix",
+		Key:      Key{CamelotNumber: 8, CamelotLetter: "A"},
+		Genre:    Genre{Name: "Techno (Peak Time / Driving)", Slug: "techno-peak-time-driving"},
+		LengthMs: 390000,
+		Release: Release{
+			Date:        "2024-07-01",
+			PublishDate: "2024-05-10",
+			Type:        ReleaseType{Name: "EP"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		filter  TrackFilter
+		matches bool
+	}{
+		{"empty", TrackFilter{}, true},
+		{"bpm in range", TrackFilter{BPMMin: 120, BPMMax: 128}, true},
+		{"bpm out of range", TrackFilter{BPMMin: 128}, false},
+		{"key", TrackFilter{Keys: []string{"8A", "9A"}}, true},
+		{"other key", TrackFilter{Keys: []string{"9A"}}, false},
+		{"genre slug", TrackFilter{Genres: []string{"Techno-Peak-Time-Driving"}}, true},
+		{"other genre", TrackFilter{Genres: []string{"House"}}, false},
+		{"date window", TrackFilter{DateFrom: "2024-01-01", DateTo: "2024-12-31"}, true},
+		{"before window", TrackFilter{DateFrom: "2024-06-01"}, false},
+		{"new release date ignored", TrackFilter{DateTo: "2024-06-01"}, true},
+		{"excluded mix", TrackFilter{ExcludeMixNames: []*regexp.Regexp{regexp.MustCompile(`(?i)extended`)}}, false},
+		{"included mix", TrackFilter{IncludeMixNames: []*regexp.Regexp{regexp.MustCompile(`(?i)original`)}}, false},
+		{"release type", TrackFilter{ReleaseTypes: []string{"ep", "album"}}, true},
+		{"too short", TrackFilter{LengthMin: 420000}, false},
+		{"exclusive only", TrackFilter{ExclusiveOnly: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := tt.filter.Match(&track); matches != tt.matches {
+				t.Errorf("Match() = %v, expected %v", matches, tt.matches)
+			}
+		})
+	}
+}
@@ -1,7 +1,146 @@
 package beatport
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
 type Genre struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
 	Slug string `json:"slug"`
 }
+
+// GenreFeed is a generated genre listing (Top 100, Hype 100 or the new releases)
+// that doesn't exist as an entity in the catalog.
+type GenreFeed struct {
+	Genre Genre
+	Name  string
+	Date  time.Time
+	Store Store
+}
+
+const (
+	GenreFeedTop      = "Top 100"
+	GenreFeedHype     = "Hype 100"
+	GenreFeedReleases = "New Releases"
+)
+
+var allGenres = Genre{
+	Name: "All Genres",
+	Slug: "all-genres",
+}
+
+func (f *GenreFeed) DirectoryName(n NamingPreferences) string {
+	_, week := f.Date.ISOWeek()
+	templateValues := map[string]string{
+		"id":         strconv.Itoa(int(f.Genre.ID)),
+		"genre":      n.Path.SanitizeForPath(f.Genre.Name),
+		"genre_slug": f.Genre.Slug,
+		"name":       n.Path.SanitizeForPath(f.Name),
+		"date":       f.Date.Format("2006-01-02"),
+		"year":       f.Date.Format("2006"),
+		"week":       fmt.Sprintf("%02d", week),
+	}
+	directoryName := ParseTemplate(n.Template, templateValues)
+	return n.Path.SanitizePath(directoryName, n.Whitespace)
+}
+
+// GetGenre returns the genre with the given ID, ID 0 stands for all genres.
+func (b *Beatport) GetGenre(id int64) (*Genre, error) {
+	if id == 0 {
+		genre := allGenres
+		return &genre, nil
+	}
+	res, err := b.fetch(
+		"GET",
+		fmt.Sprintf("/catalog/genres/%d/", id),
+		nil,
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &Genre{}
+	if err = json.NewDecoder(res.Body).Decode(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (b *Beatport) GetGenreFeed(link *Link) (*GenreFeed, error) {
+	genre, err := b.GetGenre(link.ID)
+	if err != nil {
+		return nil, err
+	}
+	feed := &GenreFeed{
+		Genre: *genre,
+		Date:  time.Now(),
+		Store: b.store,
+	}
+	switch link.Type {
+	case GenreTopLink:
+		feed.Name = GenreFeedTop
+	case GenreHypeLink:
+		feed.Name = GenreFeedHype
+	case GenreReleasesLink:
+		feed.Name = GenreFeedReleases
+	}
+	return feed, nil
+}
+
+func (b *Beatport) GetGenreTopTracks(id int64, page int, params string) (*Paginated[Track], error) {
+	endpoint := fmt.Sprintf("/catalog/genres/%d/top/100/?page=%d&%s", id, page, params)
+	if id == 0 {
+		endpoint = fmt.Sprintf("/catalog/tracks/top/100/?page=%d&%s", page, params)
+	}
+	return b.fetchTracks(endpoint)
+}
+
+func (b *Beatport) GetGenreHypeTracks(id int64, page int, params string) (*Paginated[Track], error) {
+	return b.fetchTracks(fmt.Sprintf("/catalog/genres/%d/hype/100/?page=%d&%s", id, page, params))
+}
+
+func (b *Beatport) GetGenreReleases(id int64, page int, params string) (*Paginated[Release], error) {
+	genreParam := ""
+	if id != 0 {
+		genreParam = fmt.Sprintf("genre_id=%d&", id)
+	}
+	res, err := b.fetch(
+		"GET",
+		fmt.Sprintf("/catalog/releases/?%sorder_by=-publish_date&page=%d&%s", genreParam, page, params),
+		nil,
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response Paginated[Release]
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	for i := range response.Results {
+		response.Results[i].Store = b.store
+	}
+	return &response, nil
+}
+
+func (b *Beatport) fetchTracks(endpoint string) (*Paginated[Track], error) {
+	res, err := b.fetch("GET", endpoint, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response Paginated[Track]
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	for i := range response.Results {
+		response.Results[i].Store = b.store
+	}
+	return &response, nil
+}
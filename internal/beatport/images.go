@@ -8,7 +8,16 @@ type Image struct {
 	DynamicURI string `json:"dynamic_uri"`
 }
 
+// FormattedUrl returns the URL of the image resized to size ("1400x1400"),
+// or of the uploaded image when size is "original".
 func (i *Image) FormattedUrl(size string) string {
+	if size == "original" {
+		if i.URI != "" {
+			return i.URI
+		}
+		// The largest size served when the uploaded image is missing
+		size = "1400x1400"
+	}
 	return strings.Replace(
 		i.DynamicURI,
 		"{w}x{h}",
@@ -2,6 +2,7 @@ package beatport
 
 import (
 	"strconv"
+	"strings"
 )
 
 type Key struct {
@@ -54,3 +55,10 @@ func (k *Key) Display(system string) string {
 		return ""
 	}
 }
+
+func (k *Key) Camelot() string {
+	if k.CamelotNumber == 0 {
+		return ""
+	}
+	return strconv.Itoa(k.CamelotNumber) + strings.ToUpper(k.CamelotLetter)
+}
@@ -13,19 +13,20 @@ type Label struct {
 	Slug    string    `json:"slug"`
 	Created time.Time `json:"created"`
 	Updated time.Time `json:"updated"`
+	Image   Image     `json:"image"`
 	Store   Store     `json:"store"`
 }
 
 func (l *Label) DirectoryName(n NamingPreferences) string {
 	templateValues := map[string]string{
 		"id":           strconv.Itoa(int(l.ID)),
-		"name":         SanitizeForPath(l.Name),
+		"name":         n.Path.SanitizeForPath(l.Name),
 		"slug":         l.Slug,
 		"created_date": l.Created.Format("2006-01-02"),
 		"updated_date": l.Updated.Format("2006-01-02"),
 	}
 	directoryName := ParseTemplate(n.Template, templateValues)
-	return SanitizePath(directoryName, n.Whitespace)
+	return n.Path.SanitizePath(directoryName, n.Whitespace)
 }
 
 func (l *Label) StoreUrl() string {
@@ -19,6 +19,10 @@ var (
 	LabelLink    LinkType = "labels"
 	ArtistLink   LinkType = "artists"
 
+	GenreTopLink      LinkType = "genre-top"
+	GenreHypeLink     LinkType = "genre-hype"
+	GenreReleasesLink LinkType = "genre-releases"
+
 	StoreBeatport   Store = "beatport"
 	StoreBeatsource Store = "beatsource"
 )
@@ -100,10 +104,35 @@ func (b *Beatport) ParseUrl(inputURL string) (*Link, error) {
 		idSegment = 2
 		link.Type = ArtistLink
 
+	case "genre":
+		idSegment = 2
+		link.Type = GenreTopLink
+		if segmentsLength > 3 {
+			switch segments[3] {
+			case "top-100":
+				link.Type = GenreTopLink
+			case "hype-100":
+				link.Type = GenreHypeLink
+			case "releases":
+				link.Type = GenreReleasesLink
+			default:
+				return nil, fmt.Errorf("invalid link type: %s/%s", segments[0], segments[3])
+			}
+		}
+	case "top-100":
+		link.Type = GenreTopLink
+		link.Params = u.RawQuery
+		return &link, nil
+
 	case "tracks":
 		idSegment = 1
 		link.Type = TrackLink
 	case "releases":
+		if segmentsLength == 1 {
+			link.Type = GenreReleasesLink
+			link.Params = u.RawQuery
+			return &link, nil
+		}
 		idSegment = 1
 		link.Type = ReleaseLink
 	default:
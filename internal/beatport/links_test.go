@@ -0,0 +1,35 @@
+package beatport
+
+import "testing"
+
+func TestParseUrlGenreFeeds(t *testing.T) {
+	b := &Beatport{}
+	tests := []struct {
+		url      string
+		linkType LinkType
+		id       int64
+	}{
+		{"https://www.beatport.com/genre/techno-peak-time-driving/6/top-100", GenreTopLink, 6},
+		{"https://www.beatport.com/genre/techno-peak-time-driving/6", GenreTopLink, 6},
+		{"https://www.beatport.com/genre/house/5/hype-100", GenreHypeLink, 5},
+		{"https://www.beatport.com/genre/house/5/releases", GenreReleasesLink, 5},
+		{"https://www.beatport.com/top-100", GenreTopLink, 0},
+		{"https://www.beatport.com/releases", GenreReleasesLink, 0},
+		{"https://www.beatsource.com/genre/hip-hop/1/top-100", GenreTopLink, 1},
+		{"https://api.beatport.com/v4/catalog/releases/123/", ReleaseLink, 123},
+	}
+	for _, tt := range tests {
+		link, err := b.ParseUrl(tt.url)
+		if err != nil {
+			t.Errorf("ParseUrl(%q) failed: %v", tt.url, err)
+			continue
+		}
+		if link.Type != tt.linkType || link.ID != tt.id {
+			t.Errorf("ParseUrl(%q) = %s %d, expected %s %d", tt.url, link.Type, link.ID, tt.linkType, tt.id)
+		}
+	}
+
+	if _, err := b.ParseUrl("https://www.beatport.com/genre/house/5/staff-picks"); err == nil {
+		t.Error("ParseUrl should fail for unsupported genre pages")
+	}
+}
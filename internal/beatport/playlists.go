@@ -16,6 +16,7 @@ type Playlist struct {
 	LengthMs    Duration  `json:"length_ms"`
 	CreatedDate time.Time `json:"created_date"`
 	UpdatedDate time.Time `json:"updated_date"`
+	Store       Store     `json:"store"`
 }
 
 type PlaylistItem struct {
@@ -38,8 +39,8 @@ func (p *Playlist) DirectoryName(n NamingPreferences) string {
 
 	templateValues := map[string]string{
 		"id":           strconv.Itoa(int(p.ID)),
-		"name":         SanitizeForPath(p.Name),
-		"first_genre":  SanitizeForPath(firstGenre),
+		"name":         n.Path.SanitizeForPath(p.Name),
+		"first_genre":  n.Path.SanitizeForPath(firstGenre),
 		"track_count":  NumberWithPadding(p.TrackCount, p.TrackCount, n.TrackNumberPadding),
 		"bpm_range":    bpmRange,
 		"length":       p.LengthMs.Display(),
@@ -47,7 +48,11 @@ func (p *Playlist) DirectoryName(n NamingPreferences) string {
 		"updated_date": p.UpdatedDate.Format("2006-01-02"),
 	}
 	directoryName := ParseTemplate(n.Template, templateValues)
-	return SanitizePath(directoryName, n.Whitespace)
+	return n.Path.SanitizePath(directoryName, n.Whitespace)
+}
+
+func (p *Playlist) StoreUrl() string {
+	return storeUrl(p.ID, "playlists", "share", p.Store)
 }
 
 func (b *Beatport) GetPlaylist(id int64) (*Playlist, error) {
@@ -65,6 +70,7 @@ func (b *Beatport) GetPlaylist(id int64) (*Playlist, error) {
 	if err = json.NewDecoder(res.Body).Decode(response); err != nil {
 		return nil, err
 	}
+	response.Store = b.store
 	return response, nil
 }
 
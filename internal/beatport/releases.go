@@ -17,7 +17,9 @@ type Release struct {
 	UPC           string          `json:"upc"`
 	Label         Label           `json:"label"`
 	Date          string          `json:"new_release_date"`
+	PublishDate   string          `json:"publish_date"`
 	Image         Image           `json:"image"`
+	Type          ReleaseType     `json:"type"`
 	BPMRange      ReleaseBPMRange `json:"bpm_range"`
 	TrackUrls     []string        `json:"tracks"`
 	TrackCount    int             `json:"track_count"`
@@ -25,6 +27,11 @@ type Release struct {
 	Store         Store           `json:"store"`
 }
 
+type ReleaseType struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
 type ReleaseBPMRange struct {
 	Min int `json:"min"`
 	Max int `json:"max"`
@@ -89,18 +96,18 @@ func (r *Release) DirectoryName(n NamingPreferences) string {
 
 	templateValues := map[string]string{
 		"id":             strconv.Itoa(int(r.ID)),
-		"name":           SanitizeForPath(r.Name.String()),
+		"name":           n.Path.SanitizeForPath(r.Name.String()),
 		"slug":           r.Slug,
-		"artists":        SanitizeForPath(artistsString),
-		"remixers":       SanitizeForPath(remixersString),
+		"artists":        n.Path.SanitizeForPath(artistsString),
+		"remixers":       n.Path.SanitizeForPath(remixersString),
 		"date":           r.Date,
 		"year":           r.Year(),
 		"track_count":    NumberWithPadding(r.TrackCount, r.TrackCount, n.TrackNumberPadding),
 		"bpm_range":      fmt.Sprintf("%d-%d", r.BPMRange.Min, r.BPMRange.Max),
-		"catalog_number": SanitizeForPath(r.CatalogNumber.String()),
+		"catalog_number": n.Path.SanitizeForPath(r.CatalogNumber.String()),
 		"upc":            r.UPC,
-		"label":          SanitizeForPath(r.Label.Name),
+		"label":          n.Path.SanitizeForPath(r.Label.Name),
 	}
 	directoryName := ParseTemplate(n.Template, templateValues)
-	return SanitizePath(directoryName, n.Whitespace)
+	return n.Path.SanitizePath(directoryName, n.Whitespace)
 }
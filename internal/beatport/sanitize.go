@@ -0,0 +1,221 @@
+package beatport
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// PathProfile selects the file system rules used to sanitize file and
+// directory names. The zero value is the portable profile.
+type PathProfile struct {
+	Name string
+	// Normalization is the Unicode normalization form of the names (nfc, nfd),
+	// none when empty
+	Normalization string
+	// ASCII transliterates the names to ASCII, dropping the characters
+	// without a Latin equivalent
+	ASCII bool
+}
+
+type pathRules struct {
+	// removed are the characters dropped from names, along with the control
+	// characters when removeControl is set
+	removed       string
+	removeControl bool
+	// trimTrailing are the characters a name may not end with
+	trimTrailing  string
+	reservedNames bool
+	// maxNameBytes and maxNameUnits limit a name in UTF-8 bytes and UTF-16
+	// code units, leaving room for the file extension
+	maxNameBytes int
+	maxNameUnits int
+	// maxPathUnits limits the full path in UTF-16 code units
+	maxPathUnits int
+}
+
+// reservedExtension is the room left for the file extension in names.
+const reservedExtension = 5
+
+var (
+	windowsRules = pathRules{
+		removed:       "\\/<>:\"|?*",
+		removeControl: true,
+		trimTrailing:  ". ",
+		reservedNames: true,
+		maxNameUnits:  255 - reservedExtension,
+		maxPathUnits:  259,
+	}
+
+	pathProfileRules = map[string]pathRules{
+		// portable are the rules used before the profiles were added
+		"portable": {
+			removed:      "\\/<>:\"|?*",
+			maxNameBytes: 250,
+		},
+		"posix": {
+			removed:      "/\x00",
+			maxNameBytes: 255 - reservedExtension,
+		},
+		"windows": windowsRules,
+		// FAT32 and exFAT long file names follow the Windows rules
+		"fat32": windowsRules,
+		// Samba shares are stored on a POSIX file system and read by Windows clients
+		"samba": {
+			removed:       windowsRules.removed,
+			removeControl: true,
+			trimTrailing:  windowsRules.trimTrailing,
+			reservedNames: true,
+			maxNameBytes:  255 - reservedExtension,
+			maxNameUnits:  255 - reservedExtension,
+			maxPathUnits:  windowsRules.maxPathUnits,
+		},
+	}
+
+	windowsReservedNames = []string{
+		"CON", "PRN", "AUX", "NUL",
+		"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+		"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+	}
+
+	transliterations = map[rune]string{
+		'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE",
+		'ø': "o", 'Ø': "O", 'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D",
+		'ł': "l", 'Ł': "L", 'þ': "th", 'Þ': "Th", 'ı': "i", 'ħ': "h", 'Ħ': "H",
+		'‘': "'", '’': "'", '‚': "'", '‛': "'", '“': "\"", '”': "\"", '„': "\"",
+		'–': "-", '—': "-", '‐': "-", '−': "-", '…': "...", '×': "x",
+		'«': "<<", '»': ">>", '€': "EUR", '£': "GBP", '¥': "JPY",
+	}
+)
+
+func (p PathProfile) rules() pathRules {
+	if rules, ok := pathProfileRules[p.Name]; ok {
+		return rules
+	}
+	return pathProfileRules["portable"]
+}
+
+// SanitizeForPath prepares a template value, removing the characters the
+// file system does not allow and collapsing the whitespace.
+func (p PathProfile) SanitizeForPath(s string) string {
+	return strings.Join(strings.Fields(p.clean(s)), " ")
+}
+
+// SanitizePath sanitizes a file or directory name built from a template. The
+// name is cut to the length the file system allows without splitting a
+// character, and Windows reserved names get an underscore appended.
+func (p PathProfile) SanitizePath(name string, whitespace string) string {
+	rules := p.rules()
+	name = p.clean(name)
+	if whitespace != "" {
+		name = strings.ReplaceAll(name, " ", whitespace)
+	}
+	name = strings.Join(strings.Fields(name), " ")
+	name = truncateName(name, rules.maxNameBytes, rules.maxNameUnits)
+	return p.finishName(name)
+}
+
+// FitPath cuts the name so that the path of the file in the directory stays
+// within the path length limit of the profile.
+func (p PathProfile) FitPath(directory, name, ext string) string {
+	rules := p.rules()
+	if rules.maxPathUnits == 0 {
+		return name
+	}
+	if abs, err := filepath.Abs(directory); err == nil {
+		directory = abs
+	}
+	available := rules.maxPathUnits - utf16Len(directory) - 1 - utf16Len(ext)
+	if available >= utf16Len(name) {
+		return name
+	}
+	if available < 1 {
+		available = 1
+	}
+	return p.finishName(truncateName(name, 0, available))
+}
+
+func (p PathProfile) clean(s string) string {
+	rules := p.rules()
+	switch {
+	case p.ASCII:
+		s = transliterate(s)
+	case p.Normalization == "nfc":
+		s = norm.NFC.String(s)
+	case p.Normalization == "nfd":
+		s = norm.NFD.String(s)
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(rules.removed, r) || (rules.removeControl && unicode.IsControl(r)) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// finishName applies the rules for the end of the name and the reserved
+// names, which cutting the name can change.
+func (p PathProfile) finishName(name string) string {
+	rules := p.rules()
+	if rules.trimTrailing != "" {
+		name = strings.TrimRight(name, rules.trimTrailing)
+	}
+	if rules.reservedNames {
+		base, _, _ := strings.Cut(name, ".")
+		for _, reserved := range windowsReservedNames {
+			if strings.EqualFold(strings.TrimRight(base, " "), reserved) {
+				name = base + "_" + name[len(base):]
+				break
+			}
+		}
+	}
+	return name
+}
+
+// truncateName cuts the name at a character boundary to fit both limits,
+// ignoring the limits that are zero.
+func truncateName(name string, maxBytes, maxUnits int) string {
+	units := 0
+	for i, r := range name {
+		units += utf16RuneLen(r)
+		if (maxBytes > 0 && i+utf8.RuneLen(r) > maxBytes) || (maxUnits > 0 && units > maxUnits) {
+			return name[:i]
+		}
+	}
+	return name
+}
+
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// Drops the accents split off the letters
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16RuneLen(r)
+	}
+	return n
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
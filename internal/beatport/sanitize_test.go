@@ -0,0 +1,67 @@
+package beatport
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		profile    PathProfile
+		name       string
+		whitespace string
+		want       string
+	}{
+		{PathProfile{}, `AC/DC: "Live" <Edit>?`, "", "ACDC Live Edit"},
+		{PathProfile{Name: "portable"}, "Fred again..  Marea", "_", "Fred_again..__Marea"},
+		{PathProfile{Name: "posix"}, `AC/DC: "Live"?`, "", `ACDC: "Live"?`},
+		{PathProfile{Name: "windows"}, "Fred again..", "", "Fred again"},
+		{PathProfile{Name: "windows"}, "con", "", "con_"},
+		{PathProfile{Name: "fat32"}, "COM1.Remix", "", "COM1_.Remix"},
+		{PathProfile{Name: "fat32"}, "Track\x01 Name ", "", "Track Name"},
+		{PathProfile{Name: "samba"}, "Console", "", "Console"},
+		{PathProfile{Name: "posix", Normalization: "nfd"}, "Beyonc\u00e9", "", "Beyonce\u0301"},
+		{PathProfile{Name: "posix", Normalization: "nfc"}, "Beyonce\u0301", "", "Beyonc\u00e9"},
+		{PathProfile{Name: "fat32", ASCII: true}, "Røyksopp – Ævestrasse (Dubstrumental Ré-Edit)", "", "Royksopp - AEvestrasse (Dubstrumental Re-Edit)"},
+		{PathProfile{ASCII: true}, "Weiß 東京", "", "Weiss"},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.SanitizePath(tt.name, tt.whitespace); got != tt.want {
+			t.Errorf("%+v SanitizePath(%q) = %q, want %q", tt.profile, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizePathLength(t *testing.T) {
+	name := strings.Repeat("é", 200)
+	for _, profile := range []string{"portable", "posix", "samba"} {
+		got := PathProfile{Name: profile}.SanitizePath(name, "")
+		if !utf8.ValidString(got) {
+			t.Errorf("%s: SanitizePath split a character", profile)
+		}
+		if len(got) > 250 {
+			t.Errorf("%s: SanitizePath length = %d bytes", profile, len(got))
+		}
+	}
+
+	got := PathProfile{Name: "windows"}.SanitizePath(strings.Repeat("🎵", 200), "")
+	if !utf8.ValidString(got) || utf16Len(got) > 250 {
+		t.Errorf("windows: SanitizePath length = %d UTF-16 units", utf16Len(got))
+	}
+}
+
+func TestFitPath(t *testing.T) {
+	directory := "/" + strings.Repeat("d", 200)
+	name := strings.Repeat("n", 100) + ". x"
+	got := PathProfile{Name: "fat32"}.FitPath(directory, name, ".flac")
+	if total := utf16Len(directory) + 1 + utf16Len(got) + len(".flac"); total > 259 {
+		t.Errorf("FitPath() path length = %d", total)
+	}
+	if strings.HasSuffix(got, ".") || strings.HasSuffix(got, " ") {
+		t.Errorf("FitPath() = %q ends with a dot or space", got)
+	}
+	if got := (PathProfile{Name: "posix"}).FitPath(directory, name, ".flac"); got != name {
+		t.Errorf("posix FitPath() = %q, want the name unchanged", got)
+	}
+}
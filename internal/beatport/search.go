@@ -4,17 +4,90 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
+type SearchType string
+
+const (
+	SearchTracks    SearchType = "tracks"
+	SearchReleases  SearchType = "releases"
+	SearchArtists   SearchType = "artists"
+	SearchLabels    SearchType = "labels"
+	SearchCharts    SearchType = "charts"
+	SearchPlaylists SearchType = "playlists"
+)
+
+var SearchTypes = []SearchType{
+	SearchTracks,
+	SearchReleases,
+	SearchArtists,
+	SearchLabels,
+	SearchCharts,
+	SearchPlaylists,
+}
+
+type SearchOptions struct {
+	Types   []SearchType
+	Page    int
+	PerPage int
+	OrderBy string
+	GenreID int64
+	Filter  *TrackFilter
+}
+
 type SearchResults struct {
-	Tracks   []Track   `json:"tracks"`
-	Releases []Release `json:"releases"`
+	Tracks    []Track    `json:"tracks"`
+	Releases  []Release  `json:"releases"`
+	Artists   []Artist   `json:"artists"`
+	Labels    []Label    `json:"labels"`
+	Charts    []Chart    `json:"charts"`
+	Playlists []Playlist `json:"playlists"`
 }
 
-func (b *Beatport) Search(query string) (*SearchResults, error) {
+const (
+	DefaultSearchOrder = "-publish_date"
+)
+
+func (b *Beatport) Search(query string, options SearchOptions) (*SearchResults, error) {
+	values := url.Values{}
+	values.Set("q", query)
+	values.Set("is_available_for_streaming", "true")
+
+	orderBy := options.OrderBy
+	if orderBy == "" {
+		orderBy = DefaultSearchOrder
+	}
+	values.Set("order_by", orderBy)
+
+	if len(options.Types) > 0 {
+		var types []string
+		for _, t := range options.Types {
+			types = append(types, string(t))
+		}
+		values.Set("type", strings.Join(types, ","))
+	}
+	if options.Page > 0 {
+		values.Set("page", strconv.Itoa(options.Page))
+	}
+	if options.PerPage > 0 {
+		values.Set("per_page", strconv.Itoa(options.PerPage))
+	}
+	if options.GenreID > 0 {
+		values.Set("genre_id", strconv.FormatInt(options.GenreID, 10))
+	}
+
+	params := values.Encode()
+	if options.Filter != nil {
+		if filterParams := options.Filter.TrackParams(); filterParams != "" {
+			params += "&" + filterParams
+		}
+	}
+
 	res, err := b.fetch(
 		"GET",
-		fmt.Sprintf("/catalog/search/?q=%s&order_by=-publish_date&is_available_for_streaming=true", url.QueryEscape(query)),
+		fmt.Sprintf("/catalog/search/?%s", params),
 		nil,
 		"",
 	)
@@ -26,5 +99,49 @@ func (b *Beatport) Search(query string) (*SearchResults, error) {
 	if err = json.NewDecoder(res.Body).Decode(response); err != nil {
 		return nil, err
 	}
+
+	if options.Filter != nil {
+		var tracks []Track
+		for _, track := range response.Tracks {
+			if options.Filter.Match(&track) {
+				tracks = append(tracks, track)
+			}
+		}
+		response.Tracks = tracks
+	}
+
+	for i := range response.Tracks {
+		response.Tracks[i].Store = b.store
+	}
+	for i := range response.Releases {
+		response.Releases[i].Store = b.store
+	}
+	for i := range response.Artists {
+		response.Artists[i].Store = b.store
+	}
+	for i := range response.Labels {
+		response.Labels[i].Store = b.store
+	}
+	for i := range response.Charts {
+		response.Charts[i].Store = b.store
+	}
+	for i := range response.Playlists {
+		response.Playlists[i].Store = b.store
+	}
+
 	return response, nil
 }
+
+func (r *SearchResults) Count() int {
+	return len(r.Tracks) + len(r.Releases) + len(r.Artists) +
+		len(r.Labels) + len(r.Charts) + len(r.Playlists)
+}
+
+func ParseSearchType(s string) (SearchType, error) {
+	for _, t := range SearchTypes {
+		if string(t) == s || strings.TrimSuffix(string(t), "s") == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid search type: %s", s)
+}
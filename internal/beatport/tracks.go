@@ -23,9 +23,13 @@ type Track struct {
 	Artists     Artists         `json:"artists"`
 	Remixers    Artists         `json:"remixers"`
 	PublishDate string          `json:"publish_date"`
+	Exclusive   bool            `json:"exclusive"`
 	Release     Release         `json:"release"`
 	URL         string          `json:"url"`
 	Store       Store           `json:"store"`
+
+	Position      int `json:"-"`
+	PositionTotal int `json:"-"`
 }
 
 type TrackDownload struct {
@@ -67,24 +71,25 @@ func (t *Track) Filename(n NamingPreferences) string {
 
 	templateValues := map[string]string{
 		"id":                  strconv.Itoa(int(t.ID)),
-		"name":                SanitizeForPath(t.Name.String()),
+		"name":                n.Path.SanitizeForPath(t.Name.String()),
 		"slug":                t.Slug,
-		"mix_name":            SanitizeForPath(t.MixName.String()),
-		"artists":             SanitizeForPath(artistsString),
-		"remixers":            SanitizeForPath(remixersString),
+		"mix_name":            n.Path.SanitizeForPath(t.MixName.String()),
+		"artists":             n.Path.SanitizeForPath(artistsString),
+		"remixers":            n.Path.SanitizeForPath(remixersString),
 		"number":              NumberWithPadding(t.Number, t.Release.TrackCount, n.TrackNumberPadding),
 		"length":              t.LengthMs.Display(),
 		"key":                 t.Key.Display(n.KeySystem),
 		"bpm":                 strconv.Itoa(t.BPM),
-		"genre":               SanitizeForPath(t.Genre.Name),
-		"subgenre":            SanitizeForPath(subgenre),
-		"genre_with_subgenre": SanitizeForPath(t.GenreWithSubgenre("-")),
-		"subgenre_or_genre":   SanitizeForPath(t.SubgenreOrGenre()),
+		"genre":               n.Path.SanitizeForPath(t.Genre.Name),
+		"subgenre":            n.Path.SanitizeForPath(subgenre),
+		"genre_with_subgenre": n.Path.SanitizeForPath(t.GenreWithSubgenre("-")),
+		"subgenre_or_genre":   n.Path.SanitizeForPath(t.SubgenreOrGenre()),
 		"isrc":                t.ISRC,
-		"label":               SanitizeForPath(t.Release.Label.Name),
+		"label":               n.Path.SanitizeForPath(t.Release.Label.Name),
+		"position":            NumberWithPadding(t.Position, t.PositionTotal, n.TrackNumberPadding),
 	}
 	fileName := ParseTemplate(n.Template, templateValues)
-	return SanitizePath(fileName, n.Whitespace)
+	return n.Path.SanitizePath(fileName, n.Whitespace)
 }
 
 func (b *Beatport) GetTrack(id int64) (*Track, error) {
@@ -106,6 +111,16 @@ func (b *Beatport) GetTrack(id int64) (*Track, error) {
 	return response, nil
 }
 
+// GetTracksByISRC returns the tracks with the ISRC, the same recording can
+// be on more than one release.
+func (b *Beatport) GetTracksByISRC(isrc string) ([]Track, error) {
+	paginated, err := b.fetchTracks(fmt.Sprintf("/catalog/tracks/?isrc=%s", url.QueryEscape(isrc)))
+	if err != nil {
+		return nil, err
+	}
+	return paginated.Results, nil
+}
+
 func (b *Beatport) DownloadTrack(id int64, quality string) (*TrackDownload, error) {
 	res, err := b.fetch(
 		"GET",
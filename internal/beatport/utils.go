@@ -17,6 +17,7 @@ type NamingPreferences struct {
 	ArtistsShortForm   string
 	TrackNumberPadding int
 	KeySystem          string
+	Path               PathProfile
 }
 
 func (d *Duration) Display() string {
@@ -46,39 +47,6 @@ func (s *SanitizedString) String() string {
 	return string(*s)
 }
 
-func SanitizeForPath(s string) string {
-	r := strings.NewReplacer(
-		"\\", "",
-		"/", "",
-	)
-	return strings.Join(strings.Fields(r.Replace(s)), " ")
-}
-
-func SanitizePath(name string, whitespace string) string {
-	if len(name) > 250 {
-		name = name[:250]
-	}
-
-	oldnew := []string{
-		"<", "",
-		">", "",
-		":", "",
-		"\"", "",
-		"|", "",
-		"?", "",
-		"*", "",
-	}
-
-	if whitespace != "" {
-		oldnew = append(oldnew, " ", whitespace)
-	}
-
-	r := strings.NewReplacer(oldnew...)
-	name = r.Replace(name)
-
-	return strings.Join(strings.Fields(name), " ")
-}
-
 func NumberWithPadding(value, total, padding int) string {
 	if padding == 0 {
 		padding = len(strconv.Itoa(total))
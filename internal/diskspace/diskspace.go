@@ -0,0 +1,37 @@
+// Package diskspace reports the free space of file systems.
+package diskspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrUnsupported = errors.New("free space is not available on this platform")
+
+// Free returns the bytes available to the user on the file system of path.
+// Paths that do not exist yet are resolved to their closest existing parent.
+func Free(path string) (uint64, error) {
+	path, err := existingParent(path)
+	if err != nil {
+		return 0, err
+	}
+	return free(path)
+}
+
+func existingParent(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, nil
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,17 @@
+package diskspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFree(t *testing.T) {
+	dir := t.TempDir()
+	free, err := Free(filepath.Join(dir, "not", "created", "yet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free == 0 {
+		t.Error("Free() = 0")
+	}
+}
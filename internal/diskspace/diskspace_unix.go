@@ -0,0 +1,13 @@
+//go:build unix
+
+package diskspace
+
+import "golang.org/x/sys/unix"
+
+func free(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
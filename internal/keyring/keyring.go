@@ -0,0 +1,95 @@
+// Package keyring stores secrets in the OS keyring using the command line tools
+// shipped with the OS: secret-tool (libsecret) on Linux and security on macOS.
+// There is no backend for the Windows Credential Manager.
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var (
+	ErrUnsupportedOS = errors.New("keyring is not supported on " + runtime.GOOS)
+	ErrNotFound      = errors.New("secret not found in keyring")
+)
+
+// Supported reports whether the keyring is available on this OS.
+func Supported() bool {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "darwin":
+		return true
+	default:
+		return false
+	}
+}
+
+func Get(service, user string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "username", user)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", user, "-w")
+	default:
+		return "", ErrUnsupportedOS
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	secret := strings.TrimRight(string(output), "\r\n")
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func Set(service, user, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command(
+			"secret-tool", "store",
+			"--label", fmt.Sprintf("%s (%s)", service, user),
+			"service", service, "username", user,
+		)
+		cmd.Stdin = strings.NewReader(secret)
+	case "darwin":
+		// The command is read from stdin so the secret is not visible in the
+		// process list, the hex form needs no quoting
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf(
+			"add-generic-password -U -s %s -a %s -X %s\n",
+			quote(service), quote(user), hex.EncodeToString([]byte(secret)),
+		))
+	default:
+		return ErrUnsupportedOS
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil && runtime.GOOS == "darwin" && stderr.Len() > 0 {
+		// security -i exits without an error when one of its commands fails
+		err = errors.New("add-generic-password failed")
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// quote quotes an argument of the interactive security command line.
+func quote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type S3Options struct {
+	// Endpoint of an S3-compatible service, e.g. http://localhost:9000 for MinIO.
+	// Defaults to the AWS endpoint of the region.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket in the path instead of the host name.
+	PathStyle bool
+}
+
+// S3 stores the files in a bucket of an S3-compatible object storage. The
+// requests are signed with AWS Signature Version 4.
+type S3 struct {
+	options S3Options
+	client  *http.Client
+	now     func() time.Time
+}
+
+func NewS3(options S3Options) *S3 {
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+	if options.Endpoint == "" {
+		options.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", options.Region)
+	}
+	options.Endpoint = strings.TrimSuffix(options.Endpoint, "/")
+	options.Prefix = strings.Trim(options.Prefix, "/")
+	return &S3{
+		options: options,
+		client:  &http.Client{Timeout: 10 * time.Minute},
+		now:     time.Now,
+	}
+}
+
+func (s *S3) objectUrl(key string) (*url.URL, error) {
+	u, err := url.Parse(s.options.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+	if s.options.Prefix != "" {
+		key = s.options.Prefix + "/" + key
+	}
+	if s.options.PathStyle {
+		u.Path = "/" + s.options.Bucket + "/" + key
+	} else {
+		u.Host = s.options.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	// Sends the path encoded the same way as it is signed
+	u.RawPath = escapePath(u.Path)
+	return u, nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body io.ReadSeeker, size int64) (*http.Response, error) {
+	u, err := s.objectUrl(key)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, body); err != nil {
+			return nil, err
+		}
+		payloadHash = hex.EncodeToString(hash.Sum(nil))
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = body
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash)
+
+	return s.client.Do(req)
+}
+
+func (s *S3) Put(ctx context.Context, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(ctx, http.MethodPut, key, f, info.Size())
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("put object: %s", responseError(res))
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key, localPath string) error {
+	res, err := s.do(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return fmt.Errorf("get object: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("get object: %s", responseError(res))
+	}
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		f.Close()
+		os.Remove(localPath)
+		return fmt.Errorf("get object: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
+	res, err := s.do(ctx, http.MethodHead, key, nil, 0)
+	if err != nil {
+		return false, fmt.Errorf("head object: %w", err)
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("head object: %s", res.Status)
+	}
+}
+
+func (s *S3) Remove(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("delete object: %s", responseError(res))
+	}
+	return nil
+}
+
+func (s *S3) IsLocal() bool {
+	return false
+}
+
+func responseError(res *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if len(body) == 0 {
+		return res.Status
+	}
+	return fmt.Sprintf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+}
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sign adds the AWS Signature Version 4 authorization header to the request.
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.options.Region)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{signingAlgorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := signingKey(s.options.SecretAccessKey, date, s.options.Region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, s.options.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escapePath URI-encodes every path segment as required by the canonical
+// request: every byte but the RFC 3986 unreserved characters is encoded.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/', 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
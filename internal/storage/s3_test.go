@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signingKey() = %s, want %s", got, want)
+	}
+}
+
+// fakeS3 is an in-process stand-in for an S3-compatible service using path
+// style addressing.
+type fakeS3 struct {
+	objects map[string][]byte
+	mutex   sync.Mutex
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.validSignature(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validSignature recomputes the signature of the request the way the
+// service does, from the decoded path.
+func (f *fakeS3) validSignature(r *http.Request) bool {
+	const prefix = "AWS4-HMAC-SHA256 Credential=key/20240101/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+
+	var uri strings.Builder
+	for _, c := range []byte(r.URL.Path) {
+		if c == '/' || strings.IndexByte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~", c) >= 0 {
+			uri.WriteByte(c)
+		} else {
+			fmt.Fprintf(&uri, "%%%02X", c)
+		}
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		uri.String(),
+		"",
+		"host:" + r.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + r.Header.Get("X-Amz-Date") + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n20240101/eu-west-1/s3/aws4_request\n" + hex.EncodeToString(hash[:])
+	signature := hex.EncodeToString(hmacSHA256(signingKey("secret", "20240101", "eu-west-1", "s3"), stringToSign))
+	return strings.TrimPrefix(auth, prefix) == signature
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s := NewS3(S3Options{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Bucket:          "music",
+		Prefix:          "/beatportdl/",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	})
+	s.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	dir := t.TempDir()
+	local := filepath.Join(dir, "track.flac")
+	if err := os.WriteFile(local, []byte("audio"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	key := "Label/Artist & Friend - Track: 100% (Original Mix) [$@=+].flac"
+	if exists, err := s.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("Exists() = %v, %v before Put", exists, err)
+	}
+	if err := s.Put(ctx, key, local); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	if _, ok := fake.objects["/music/beatportdl/"+key]; !ok {
+		t.Fatalf("object not stored under the prefix: %v", fake.objects)
+	}
+	if exists, err := s.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("Exists() = %v, %v after Put", exists, err)
+	}
+
+	fetched := filepath.Join(dir, "fetched.flac")
+	if err := s.Get(ctx, key, fetched); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(fetched); string(data) != "audio" {
+		t.Errorf("Get() wrote %q", data)
+	}
+
+	if err := s.Remove(ctx, key); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if exists, _ := s.Exists(ctx, key); exists {
+		t.Error("object exists after Remove()")
+	}
+}
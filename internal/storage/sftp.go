@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type SFTPOptions struct {
+	Host         string
+	Port         int
+	User         string
+	IdentityFile string
+	// Directory on the server the keys are relative to
+	Directory string
+}
+
+// SFTP stores the files on a server using the sftp client shipped with
+// OpenSSH in batch mode, so that the existing SSH configuration, agent and
+// known hosts are used.
+type SFTP struct {
+	options SFTPOptions
+}
+
+func NewSFTP(options SFTPOptions) *SFTP {
+	return &SFTP{options: options}
+}
+
+func (s *SFTP) remotePath(key string) string {
+	if s.options.Directory == "" {
+		return key
+	}
+	return path.Join(s.options.Directory, key)
+}
+
+func (s *SFTP) args() []string {
+	args := []string{"-b", "-", "-o", "BatchMode=yes"}
+	if s.options.Port != 0 {
+		args = append(args, "-P", strconv.Itoa(s.options.Port))
+	}
+	if s.options.IdentityFile != "" {
+		args = append(args, "-i", s.options.IdentityFile)
+	}
+	destination := s.options.Host
+	if s.options.User != "" {
+		destination = s.options.User + "@" + destination
+	}
+	return append(args, destination)
+}
+
+// run executes the batch commands. The sftp client aborts on the first
+// command that fails unless it is prefixed with a dash.
+func (s *SFTP) run(ctx context.Context, commands ...string) error {
+	cmd := exec.CommandContext(ctx, "sftp", s.args()...)
+	cmd.Stdin = strings.NewReader(strings.Join(commands, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Put uploads the file under a hidden part name and renames it once the
+// upload completed, so an interrupted upload never leaves a truncated file
+// at the key. SFTP rename does not replace, so the existing file is removed
+// first.
+func (s *SFTP) Put(ctx context.Context, key, localPath string) error {
+	remote := s.remotePath(key)
+	part := partPath(remote)
+	var commands []string
+	for _, dir := range parentDirs(remote) {
+		commands = append(commands, "-mkdir "+quote(dir))
+	}
+	commands = append(commands,
+		"put "+quote(localPath)+" "+quote(part),
+		"-rm "+quote(remote),
+		"rename "+quote(part)+" "+quote(remote),
+	)
+	if err := s.run(ctx, commands...); err != nil {
+		return fmt.Errorf("sftp put: %w", err)
+	}
+	return nil
+}
+
+func (s *SFTP) Get(ctx context.Context, key, localPath string) error {
+	if err := s.run(ctx, "get "+quote(s.remotePath(key))+" "+quote(localPath)); err != nil {
+		return fmt.Errorf("sftp get: %w", err)
+	}
+	return nil
+}
+
+func (s *SFTP) Exists(ctx context.Context, key string) (bool, error) {
+	err := s.run(ctx, "ls "+quote(s.remotePath(key)))
+	if err == nil {
+		return true, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && strings.Contains(strings.ToLower(err.Error()), "not found") {
+		return false, nil
+	}
+	return false, fmt.Errorf("sftp ls: %w", err)
+}
+
+func (s *SFTP) Remove(ctx context.Context, key string) error {
+	if err := s.run(ctx, "rm "+quote(s.remotePath(key))); err != nil {
+		return fmt.Errorf("sftp rm: %w", err)
+	}
+	return nil
+}
+
+func (s *SFTP) IsLocal() bool {
+	return false
+}
+
+func partPath(p string) string {
+	return path.Join(path.Dir(p), "."+path.Base(p)+".part")
+}
+
+// parentDirs returns every parent directory of the path, outermost first.
+func parentDirs(p string) []string {
+	var dirs []string
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	return dirs
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
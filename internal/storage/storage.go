@@ -0,0 +1,105 @@
+// Package storage commits the downloaded files to the configured backend. The
+// files are always staged in a local directory first, so that they can be
+// tagged, and addressed by a slash separated key relative to that directory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type Storage interface {
+	// Put stores the local file under the key.
+	Put(ctx context.Context, key, localPath string) error
+	// Get copies the file stored under the key to the local path.
+	Get(ctx context.Context, key, localPath string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Remove(ctx context.Context, key string) error
+	// IsLocal reports whether the staged files are already in their final location.
+	IsLocal() bool
+}
+
+// Local stores the files in a directory of the local file system.
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
+
+func (l *Local) Put(_ context.Context, key, localPath string) error {
+	return moveFile(localPath, l.path(key))
+}
+
+func (l *Local) Get(_ context.Context, key, localPath string) error {
+	return copyFile(l.path(key), localPath)
+}
+
+func (l *Local) Exists(_ context.Context, key string) (bool, error) {
+	_, err := os.Stat(l.path(key))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (l *Local) Remove(_ context.Context, key string) error {
+	return os.Remove(l.path(key))
+}
+
+func (l *Local) IsLocal() bool {
+	return true
+}
+
+func moveFile(from, to string) error {
+	if filepath.Clean(from) == filepath.Clean(to) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0760); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	// Rename fails across file systems
+	if err := copyFile(from, to); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
+
+func copyFile(from, to string) error {
+	if filepath.Clean(from) == filepath.Clean(to) {
+		return nil
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(to), 0760); err != nil {
+		return err
+	}
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return fmt.Errorf("copy file: %w", err)
+	}
+	return dst.Close()
+}
@@ -118,6 +118,11 @@ func (f *File) SampleRate() int {
 	return int(C.taglib_audioproperties_samplerate(f.props))
 }
 
+// Bitrate returns the average bitrate in kbps.
+func (f *File) Bitrate() int {
+	return int(C.taglib_audioproperties_bitrate(f.props))
+}
+
 // Complex Properties API
 type Picture struct {
 	MimeType    string
@@ -0,0 +1,318 @@
+package tracklist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns maps the accepted header names to the entry fields.
+var csvColumns = map[string]string{
+	"artist":      "artists",
+	"artists":     "artists",
+	"title":       "title",
+	"name":        "title",
+	"track":       "title",
+	"track title": "title",
+	"mix":         "mix",
+	"mix name":    "mix",
+	"version":     "mix",
+	"remix":       "mix",
+	"isrc":        "isrc",
+	"url":         "url",
+	"link":        "url",
+	"duration":    "duration",
+	"length":      "duration",
+	"time":        "duration",
+}
+
+// ParseCSV reads a spreadsheet export with a header row naming the artist,
+// title, mix, isrc, url and duration columns. Without a title column every
+// row is read as an "Artists - Title (Mix)" line. A zero comma detects
+// "," or ";" from the header.
+func ParseCSV(r io.Reader, comma rune) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if comma == 0 {
+		header, _, _ := bytes.Cut(data, []byte("\n"))
+		comma = ','
+		if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+			comma = ';'
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		field, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]
+		if _, seen := columns[field]; ok && !seen {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		if _, ok := columns["url"]; !ok {
+			var entries []Entry
+			for _, record := range records {
+				if len(record) == 0 {
+					continue
+				}
+				if entry, ok := ParseLine(record[0]); ok {
+					entries = append(entries, entry)
+				}
+			}
+			return entries, nil
+		}
+	}
+
+	var entries []Entry
+	for _, record := range records[1:] {
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		entry := Entry{
+			Artists: get("artists"),
+			Title:   get("title"),
+			MixName: get("mix"),
+			ISRC:    get("isrc"),
+			URL:     get("url"),
+		}
+		if entry.Title == "" && entry.URL == "" && entry.ISRC == "" {
+			continue
+		}
+		if entry.MixName == "" {
+			entry.Title, entry.MixName = splitMixName(entry.Title)
+		}
+		entry.Duration, _ = ParseDuration(get("duration"))
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ParseM3U reads the #EXTINF titles of an extended playlist, falling back
+// to the file names of the paths. Links are kept as they are.
+func ParseM3U(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var info *Entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "\ufeff")
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			seconds, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if i := strings.IndexByte(seconds, ' '); i >= 0 {
+				// Drops attributes like tvg-id="..."
+				seconds = seconds[:i]
+			}
+			entry, ok := ParseLine(title)
+			if !ok {
+				info = nil
+				continue
+			}
+			if value, err := strconv.Atoi(seconds); err == nil && value > 0 {
+				entry.Duration = time.Duration(value) * time.Second
+			}
+			info = &entry
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://"):
+			entries = append(entries, Entry{URL: line})
+			info = nil
+		default:
+			if info != nil {
+				entries = append(entries, *info)
+				info = nil
+				continue
+			}
+			name := path.Base(strings.ReplaceAll(line, "\\", "/"))
+			name = strings.TrimSuffix(name, path.Ext(name))
+			if entry, ok := ParseLine(name); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+type rekordboxLibrary struct {
+	Tracks []struct {
+		ID        string `xml:"TrackID,attr"`
+		Name      string `xml:"Name,attr"`
+		Artist    string `xml:"Artist,attr"`
+		Mix       string `xml:"Mix,attr"`
+		TotalTime int    `xml:"TotalTime,attr"`
+	} `xml:"COLLECTION>TRACK"`
+	Playlists []rekordboxNode `xml:"PLAYLISTS>NODE"`
+}
+
+type rekordboxNode struct {
+	Nodes  []rekordboxNode `xml:"NODE"`
+	Tracks []struct {
+		Key string `xml:"Key,attr"`
+	} `xml:"TRACK"`
+}
+
+// ParseRekordbox reads a Rekordbox XML export. The tracks of the playlists
+// are returned in playlist order, or the whole collection when the export
+// has no playlists.
+func ParseRekordbox(r io.Reader) ([]Entry, error) {
+	var library rekordboxLibrary
+	if err := xml.NewDecoder(r).Decode(&library); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]Entry, len(library.Tracks))
+	var collection []Entry
+	for _, track := range library.Tracks {
+		entry := Entry{
+			Artists:  track.Artist,
+			Title:    track.Name,
+			MixName:  track.Mix,
+			Duration: time.Duration(track.TotalTime) * time.Second,
+		}
+		if entry.MixName == "" {
+			entry.Title, entry.MixName = splitMixName(entry.Title)
+		}
+		entries[track.ID] = entry
+		collection = append(collection, entry)
+	}
+
+	var keys []string
+	var walk func(nodes []rekordboxNode)
+	walk = func(nodes []rekordboxNode) {
+		for _, node := range nodes {
+			for _, track := range node.Tracks {
+				keys = append(keys, track.Key)
+			}
+			walk(node.Nodes)
+		}
+	}
+	walk(library.Playlists)
+	if len(keys) == 0 {
+		return collection, nil
+	}
+
+	var playlist []Entry
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if entry, ok := entries[key]; ok && !seen[key] {
+			seen[key] = true
+			playlist = append(playlist, entry)
+		}
+	}
+	return playlist, nil
+}
+
+type traktorLibrary struct {
+	Entries []struct {
+		Title    string `xml:"TITLE,attr"`
+		Artist   string `xml:"ARTIST,attr"`
+		Location struct {
+			Volume string `xml:"VOLUME,attr"`
+			Dir    string `xml:"DIR,attr"`
+			File   string `xml:"FILE,attr"`
+		} `xml:"LOCATION"`
+		Info struct {
+			Mix      string `xml:"MIX,attr"`
+			Playtime int    `xml:"PLAYTIME,attr"`
+		} `xml:"INFO"`
+	} `xml:"COLLECTION>ENTRY"`
+	Playlists []traktorNode `xml:"PLAYLISTS>NODE"`
+}
+
+type traktorNode struct {
+	Nodes   []traktorNode `xml:"SUBNODES>NODE"`
+	Entries []struct {
+		PrimaryKey struct {
+			Key string `xml:"KEY,attr"`
+		} `xml:"PRIMARYKEY"`
+	} `xml:"PLAYLIST>ENTRY"`
+}
+
+// ParseTraktor reads a Traktor NML collection or playlist export. Like
+// ParseRekordbox it prefers the playlist order over the collection.
+func ParseTraktor(r io.Reader) ([]Entry, error) {
+	var library traktorLibrary
+	if err := xml.NewDecoder(r).Decode(&library); err != nil {
+		return nil, err
+	}
+	if len(library.Entries) == 0 {
+		return nil, errors.New("no collection entries found")
+	}
+
+	entries := make(map[string]Entry, len(library.Entries))
+	var collection []Entry
+	for _, item := range library.Entries {
+		entry := Entry{
+			Artists:  item.Artist,
+			Title:    item.Title,
+			MixName:  item.Info.Mix,
+			Duration: time.Duration(item.Info.Playtime) * time.Second,
+		}
+		if entry.Title == "" {
+			name := strings.TrimSuffix(item.Location.File, path.Ext(item.Location.File))
+			parsed, ok := ParseLine(name)
+			if !ok {
+				continue
+			}
+			parsed.Duration = entry.Duration
+			entry = parsed
+		} else if entry.MixName == "" {
+			entry.Title, entry.MixName = splitMixName(entry.Title)
+		}
+		// Playlists reference the entries by "VOLUME/DIR/FILE"
+		entries[item.Location.Volume+item.Location.Dir+item.Location.File] = entry
+		collection = append(collection, entry)
+	}
+
+	var keys []string
+	var walk func(nodes []traktorNode)
+	walk = func(nodes []traktorNode) {
+		for _, node := range nodes {
+			for _, item := range node.Entries {
+				keys = append(keys, item.PrimaryKey.Key)
+			}
+			walk(node.Nodes)
+		}
+	}
+	walk(library.Playlists)
+	if len(keys) == 0 {
+		return collection, nil
+	}
+
+	var playlist []Entry
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if entry, ok := entries[key]; ok && !seen[key] {
+			seen[key] = true
+			playlist = append(playlist, entry)
+		}
+	}
+	return playlist, nil
+}
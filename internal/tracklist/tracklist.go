@@ -0,0 +1,178 @@
+package tracklist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported track list format")
+
+// Entry is a track of an imported list. URL is set for lines that are
+// already store links, the other fields describe a track to look up.
+type Entry struct {
+	Artists  string
+	Title    string
+	MixName  string
+	ISRC     string
+	URL      string
+	Duration time.Duration
+}
+
+func (e Entry) String() string {
+	if e.URL != "" {
+		return e.URL
+	}
+	s := e.Title
+	if e.Artists != "" {
+		s = e.Artists + " - " + s
+	}
+	if e.MixName != "" {
+		s += " (" + e.MixName + ")"
+	}
+	return s
+}
+
+// Extensions are the file extensions Parse accepts.
+var Extensions = []string{".txt", ".csv", ".tsv", ".m3u", ".m3u8", ".xml", ".nml"}
+
+func IsSupported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads a track list, picking the format by the file extension.
+func Parse(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		return ParseText(file)
+	case ".csv":
+		return ParseCSV(file, 0)
+	case ".tsv":
+		return ParseCSV(file, '\t')
+	case ".m3u", ".m3u8":
+		return ParseM3U(file)
+	case ".xml":
+		return ParseRekordbox(file)
+	case ".nml":
+		return ParseTraktor(file)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+}
+
+// ParseText reads one track per line, either a store link or an
+// "Artists - Title (Mix)" line. Blank lines and # comments are skipped.
+func ParseText(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if entry, ok := ParseLine(line); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+var (
+	linePrefixRegex   = regexp.MustCompile(`^(\[?\d{1,2}(:\d{2}){1,2}\]?\s+|\d{1,3}[.)]\s+)`)
+	artistSeparators  = []string{" – ", " — ", " - "}
+	trailingMixRegex  = regexp.MustCompile(`\s*[(\[]([^()\[\]]+)[)\]]\s*$`)
+	featuringMixRegex = regexp.MustCompile(`(?i)^(feat\.?|ft\.?|featuring)\s`)
+)
+
+// ParseLine splits an "Artists - Title (Mix)" line. Leading track numbers
+// and cue times ("01.", "12)", "[00:42:10]") are dropped, and lines
+// containing "://" are kept whole as links.
+func ParseLine(line string) (Entry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Entry{}, false
+	}
+	if strings.Contains(line, "://") {
+		return Entry{URL: line}, true
+	}
+	line = strings.TrimSpace(linePrefixRegex.ReplaceAllString(line, ""))
+
+	var entry Entry
+	entry.Title = line
+	for _, separator := range artistSeparators {
+		artists, title, found := strings.Cut(line, separator)
+		if !found {
+			continue
+		}
+		if _, err := strconv.Atoi(artists); err == nil && strings.Contains(title, separator) {
+			// "01 - Artists - Title"
+			artists, title, _ = strings.Cut(title, separator)
+		}
+		entry.Artists = strings.TrimSpace(artists)
+		entry.Title = strings.TrimSpace(title)
+		break
+	}
+	entry.Title, entry.MixName = splitMixName(entry.Title)
+	if entry.Title == "" {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// splitMixName cuts the trailing "(Mix)" or "[Mix]" off a title, leaving
+// featured artists in the title.
+func splitMixName(title string) (string, string) {
+	match := trailingMixRegex.FindStringSubmatchIndex(title)
+	if match == nil {
+		return strings.TrimSpace(title), ""
+	}
+	mix := strings.TrimSpace(title[match[2]:match[3]])
+	if featuringMixRegex.MatchString(mix) {
+		return strings.TrimSpace(title), ""
+	}
+	return strings.TrimSpace(title[:match[0]]), mix
+}
+
+// ParseDuration reads "5:32", "1:05:32" or a number of seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if !strings.Contains(s, ":") {
+		seconds, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	var total time.Duration
+	for _, part := range strings.Split(s, ":") {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		total = total*60 + time.Duration(value*float64(time.Second))
+	}
+	return total, nil
+}
@@ -0,0 +1,159 @@
+package tracklist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Entry
+	}{
+		{"FISHER – Losing It (Original Mix)", Entry{Artists: "FISHER", Title: "Losing It", MixName: "Original Mix"}},
+		{"01. Chris Lake, Aatig - Turn Off The Lights [Extended Mix]", Entry{Artists: "Chris Lake, Aatig", Title: "Turn Off The Lights", MixName: "Extended Mix"}},
+		{"[01:02:30] Bicep — Glue", Entry{Artists: "Bicep", Title: "Glue"}},
+		{"07 - Fred again.. - Marea (We've Lost Dancing)", Entry{Artists: "Fred again..", Title: "Marea", MixName: "We've Lost Dancing"}},
+		{"Mochakk - Jealous (feat. Someone)", Entry{Artists: "Mochakk", Title: "Jealous (feat. Someone)"}},
+		{"Untitled", Entry{Title: "Untitled"}},
+		{"work:https://www.beatport.com/track/x/1", Entry{URL: "work:https://www.beatport.com/track/x/1"}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLine(tt.line)
+		if !ok || got != tt.want {
+			t.Errorf("ParseLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"5:32":    332 * time.Second,
+		"1:05:32": 3932 * time.Second,
+		"332":     332 * time.Second,
+		"":        0,
+	}
+	for s, want := range tests {
+		if got, err := ParseDuration(s); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "\xef\xbb\xbfArtist;Track Title;Version;ISRC;Length\n" +
+		"FISHER;Losing It;Original Mix;USUS11800001;5:32\n" +
+		"Bicep;Glue (Extended Mix);;;\n" +
+		";;;;\n"
+	got, err := ParseCSV(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Artists: "FISHER", Title: "Losing It", MixName: "Original Mix", ISRC: "USUS11800001", Duration: 332 * time.Second},
+		{Artists: "Bicep", Title: "Glue", MixName: "Extended Mix"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCSV() = %+v, want %+v", got, want)
+	}
+
+	got, err = ParseCSV(strings.NewReader("FISHER - Losing It\nhttps://www.beatport.com/track/x/1\n"), ',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Entry{{Artists: "FISHER", Title: "Losing It"}, {URL: "https://www.beatport.com/track/x/1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCSV() without header = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseM3U(t *testing.T) {
+	input := "#EXTM3U\n" +
+		"#EXTINF:332,FISHER - Losing It (Original Mix)\n" +
+		"/music/fisher.flac\n" +
+		"C:\\Music\\Bicep - Glue.mp3\n" +
+		"https://www.beatport.com/track/x/1\n"
+	got, err := ParseM3U(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Artists: "FISHER", Title: "Losing It", MixName: "Original Mix", Duration: 332 * time.Second},
+		{Artists: "Bicep", Title: "Glue"},
+		{URL: "https://www.beatport.com/track/x/1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseM3U() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseRekordbox(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<DJ_PLAYLISTS Version="1.0.0">
+  <COLLECTION Entries="2">
+    <TRACK TrackID="1" Name="Losing It" Artist="FISHER" Mix="Original Mix" TotalTime="332"/>
+    <TRACK TrackID="2" Name="Glue (Extended Mix)" Artist="Bicep" TotalTime="401"/>
+  </COLLECTION>
+  <PLAYLISTS>
+    <NODE Type="0" Name="ROOT" Count="1">
+      <NODE Name="Set" Type="1" KeyType="0" Entries="3">
+        <TRACK Key="2"/>
+        <TRACK Key="1"/>
+        <TRACK Key="2"/>
+      </NODE>
+    </NODE>
+  </PLAYLISTS>
+</DJ_PLAYLISTS>`
+	got, err := ParseRekordbox(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Artists: "Bicep", Title: "Glue", MixName: "Extended Mix", Duration: 401 * time.Second},
+		{Artists: "FISHER", Title: "Losing It", MixName: "Original Mix", Duration: 332 * time.Second},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRekordbox() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseTraktor(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8" standalone="no" ?>
+<NML VERSION="19">
+  <COLLECTION ENTRIES="2">
+    <ENTRY TITLE="Losing It" ARTIST="FISHER">
+      <LOCATION DIR="/:Music/:" FILE="fisher.flac" VOLUME="Macintosh HD"></LOCATION>
+      <INFO MIX="Original Mix" PLAYTIME="332"></INFO>
+    </ENTRY>
+    <ENTRY>
+      <LOCATION DIR="/:Music/:" FILE="Bicep - Glue.mp3" VOLUME="Macintosh HD"></LOCATION>
+      <INFO PLAYTIME="401"></INFO>
+    </ENTRY>
+  </COLLECTION>
+  <PLAYLISTS>
+    <NODE TYPE="FOLDER" NAME="$ROOT">
+      <SUBNODES COUNT="1">
+        <NODE TYPE="PLAYLIST" NAME="Set">
+          <PLAYLIST ENTRIES="2" TYPE="LIST">
+            <ENTRY><PRIMARYKEY TYPE="TRACK" KEY="Macintosh HD/:Music/:Bicep - Glue.mp3"></PRIMARYKEY></ENTRY>
+            <ENTRY><PRIMARYKEY TYPE="TRACK" KEY="Macintosh HD/:Music/:fisher.flac"></PRIMARYKEY></ENTRY>
+          </PLAYLIST>
+        </NODE>
+      </SUBNODES>
+    </NODE>
+  </PLAYLISTS>
+</NML>`
+	got, err := ParseTraktor(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Artists: "Bicep", Title: "Glue", Duration: 401 * time.Second},
+		{Artists: "FISHER", Title: "Losing It", MixName: "Original Mix", Duration: 332 * time.Second},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTraktor() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package verify
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+var errUnexpectedEnd = errors.New("unexpected end of file")
+
+// bitReader reads big endian bit fields from a stream, keeping the FLAC
+// CRC-8 and CRC-16 of the bytes read since the last resetCRC.
+type bitReader struct {
+	r     *bufio.Reader
+	cur   byte
+	nbits uint
+	pos   int64
+	crc8  byte
+	crc16 uint16
+}
+
+func (r *bitReader) fill() error {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errUnexpectedEnd
+		}
+		return err
+	}
+	r.cur, r.nbits = b, 8
+	r.pos++
+	r.crc8 = crc8Table[r.crc8^b]
+	r.crc16 = r.crc16<<8 ^ crc16Table[byte(r.crc16>>8)^b]
+	return nil
+}
+
+func (r *bitReader) readBits(n uint) (uint64, error) {
+	var v uint64
+	for n > 0 {
+		if r.nbits == 0 {
+			if err := r.fill(); err != nil {
+				return 0, err
+			}
+		}
+		take := min(r.nbits, n)
+		b := (r.cur >> (r.nbits - take)) & byte(1<<take-1)
+		v = v<<take | uint64(b)
+		r.nbits -= take
+		n -= take
+	}
+	return v, nil
+}
+
+func (r *bitReader) readSigned(n uint) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	v, err := r.readBits(n)
+	if err != nil {
+		return 0, err
+	}
+	return int64(v<<(64-n)) >> (64 - n), nil
+}
+
+// readUnary returns the number of zero bits before the next one bit.
+func (r *bitReader) readUnary() (uint64, error) {
+	var count uint64
+	for {
+		if r.nbits == 0 {
+			if err := r.fill(); err != nil {
+				return 0, err
+			}
+		}
+		b := r.cur << (8 - r.nbits)
+		if b == 0 {
+			count += uint64(r.nbits)
+			r.nbits = 0
+			continue
+		}
+		zeros := uint(bits.LeadingZeros8(b))
+		count += uint64(zeros)
+		r.nbits -= zeros + 1
+		return count, nil
+	}
+}
+
+func (r *bitReader) align() {
+	r.nbits = 0
+}
+
+// skip discards the next n bytes after aligning to a byte.
+func (r *bitReader) skip(n int) error {
+	r.align()
+	discarded, err := r.r.Discard(n)
+	r.pos += int64(discarded)
+	if errors.Is(err, io.EOF) {
+		return errUnexpectedEnd
+	}
+	return err
+}
+
+// peek returns the next n bytes or less at the end of the stream, once
+// aligned to a byte.
+func (r *bitReader) peek(n int) ([]byte, error) {
+	r.align()
+	data, err := r.r.Peek(n)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return data, err
+}
+
+// bytePos returns the offset of the next byte once aligned.
+func (r *bitReader) bytePos() int64 {
+	return r.pos
+}
+
+func (r *bitReader) resetCRC() {
+	r.crc8, r.crc16 = 0, 0
+}
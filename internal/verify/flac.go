@@ -0,0 +1,598 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+type streamInfo struct {
+	sampleRate   uint64
+	channels     int
+	bps          uint
+	totalSamples uint64
+	md5          [16]byte
+}
+
+var flacSampleRates = [...]uint64{0, 88200, 176400, 192000, 8000, 16000, 22050, 24000, 32000, 44100, 48000, 96000}
+
+// verifyFLAC decodes every frame, checking the frame CRCs, the sample count
+// and the MD5 signature of the decoded audio against STREAMINFO.
+func verifyFLAC(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := decodeFLAC(f)
+	if errors.Is(err, errUnexpectedEnd) {
+		return nil, corrupt("truncated flac stream")
+	}
+	return info, err
+}
+
+func decodeFLAC(src io.Reader) (*Info, error) {
+	r := &bitReader{r: bufio.NewReaderSize(src, 64<<10)}
+	if err := skipID3v2(r); err != nil {
+		return nil, err
+	}
+	if marker, err := r.peek(4); err != nil {
+		return nil, err
+	} else if !bytes.Equal(marker, []byte("fLaC")) {
+		return nil, corrupt("missing flac stream marker")
+	}
+	if err := r.skip(4); err != nil {
+		return nil, err
+	}
+
+	var si *streamInfo
+	for last := false; !last; {
+		flag, err := r.readBits(1)
+		if err != nil {
+			return nil, err
+		}
+		last = flag == 1
+		blockType, _ := r.readBits(7)
+		length, err := r.readBits(24)
+		if err != nil {
+			return nil, err
+		}
+		if si == nil {
+			if blockType != 0 || length != 34 {
+				return nil, corrupt("missing STREAMINFO block")
+			}
+			if si, err = readStreamInfo(r); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if blockType == 127 {
+			return nil, corrupt("invalid metadata block type")
+		}
+		if err := r.skip(int(length)); err != nil {
+			return nil, err
+		}
+	}
+	if si.sampleRate == 0 || si.channels == 0 {
+		return nil, corrupt("invalid STREAMINFO")
+	}
+
+	d := &flacDecoder{r: r, si: si, hash: md5.New()}
+	var samples uint64
+	for {
+		rest, err := r.peek(129)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 || len(rest) == 128 && bytes.HasPrefix(rest, []byte("TAG")) {
+			// The end of the stream or an ID3v1 tag
+			break
+		}
+		n, err := d.decodeFrame()
+		if err != nil {
+			return nil, err
+		}
+		samples += n
+	}
+
+	if samples == 0 {
+		return nil, corrupt("no audio frames")
+	}
+	if si.totalSamples != 0 && samples != si.totalSamples {
+		return nil, corrupt("decoded %d samples, STREAMINFO declares %d", samples, si.totalSamples)
+	}
+	if si.md5 != [16]byte{} && !bytes.Equal(d.hash.Sum(nil), si.md5[:]) {
+		return nil, corrupt("MD5 signature mismatch")
+	}
+
+	return &Info{
+		Duration: time.Duration(samples) * time.Second / time.Duration(si.sampleRate),
+	}, nil
+}
+
+func skipID3v2(r *bitReader) error {
+	data, err := r.peek(10)
+	if err != nil || len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return err
+	}
+	size := int(data[6]&0x7f)<<21 | int(data[7]&0x7f)<<14 | int(data[8]&0x7f)<<7 | int(data[9]&0x7f)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10
+	}
+	return r.skip(size)
+}
+
+func readStreamInfo(r *bitReader) (*streamInfo, error) {
+	si := &streamInfo{}
+	// Block and frame sizes
+	if _, err := r.readBits(16 + 16 + 24 + 24); err != nil {
+		return nil, err
+	}
+	si.sampleRate, _ = r.readBits(20)
+	channels, _ := r.readBits(3)
+	si.channels = int(channels) + 1
+	bps, _ := r.readBits(5)
+	si.bps = uint(bps) + 1
+	var err error
+	if si.totalSamples, err = r.readBits(36); err != nil {
+		return nil, err
+	}
+	for i := range si.md5 {
+		b, err := r.readBits(8)
+		if err != nil {
+			return nil, err
+		}
+		si.md5[i] = byte(b)
+	}
+	return si, nil
+}
+
+type flacDecoder struct {
+	r        *bitReader
+	si       *streamInfo
+	hash     hash.Hash
+	channels [8][]int64
+	buf      []byte
+}
+
+// decodeFrame decodes one frame, adds its samples to the MD5 hash and
+// returns the number of samples per channel.
+func (d *flacDecoder) decodeFrame() (uint64, error) {
+	r := d.r
+	start := r.bytePos()
+	r.resetCRC()
+
+	sync, err := r.readBits(14)
+	if err != nil {
+		return 0, err
+	}
+	if sync != 0x3ffe {
+		return 0, corrupt("lost frame sync at byte %d", start)
+	}
+	reserved, _ := r.readBits(1)
+	r.readBits(1) // Blocking strategy
+	blockSizeCode, _ := r.readBits(4)
+	sampleRateCode, _ := r.readBits(4)
+	channelCode, _ := r.readBits(4)
+	sampleSizeCode, _ := r.readBits(3)
+	reserved2, err := r.readBits(1)
+	if err != nil {
+		return 0, err
+	}
+	if reserved != 0 || reserved2 != 0 {
+		return 0, corrupt("invalid frame header at byte %d", start)
+	}
+	if err := d.skipCodedNumber(); err != nil {
+		return 0, err
+	}
+
+	var blockSize int
+	switch {
+	case blockSizeCode == 0:
+		return 0, corrupt("invalid block size at byte %d", start)
+	case blockSizeCode == 1:
+		blockSize = 192
+	case blockSizeCode <= 5:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		v, err := r.readBits(8)
+		if err != nil {
+			return 0, err
+		}
+		blockSize = int(v) + 1
+	case blockSizeCode == 7:
+		v, err := r.readBits(16)
+		if err != nil {
+			return 0, err
+		}
+		blockSize = int(v) + 1
+	default:
+		blockSize = 256 << (blockSizeCode - 8)
+	}
+
+	switch sampleRateCode {
+	case 12:
+		_, err = r.readBits(8)
+	case 13, 14:
+		_, err = r.readBits(16)
+	case 15:
+		return 0, corrupt("invalid sample rate at byte %d", start)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	bps := d.si.bps
+	switch sampleSizeCode {
+	case 0:
+	case 1:
+		bps = 8
+	case 2:
+		bps = 12
+	case 4:
+		bps = 16
+	case 5:
+		bps = 20
+	case 6:
+		bps = 24
+	case 7:
+		bps = 32
+	default:
+		return 0, corrupt("invalid sample size at byte %d", start)
+	}
+
+	var channels int
+	switch {
+	case channelCode < 8:
+		channels = int(channelCode) + 1
+	case channelCode <= 10:
+		channels = 2
+	default:
+		return 0, corrupt("invalid channel assignment at byte %d", start)
+	}
+	if channels != d.si.channels {
+		return 0, corrupt("frame at byte %d has %d channels, STREAMINFO declares %d", start, channels, d.si.channels)
+	}
+
+	headerCRC := r.crc8
+	crc, err := r.readBits(8)
+	if err != nil {
+		return 0, err
+	}
+	if byte(crc) != headerCRC {
+		return 0, corrupt("frame header CRC mismatch at byte %d", start)
+	}
+
+	for ch := 0; ch < channels; ch++ {
+		chBps := bps
+		switch {
+		case channelCode == 8 && ch == 1, channelCode == 9 && ch == 0, channelCode == 10 && ch == 1:
+			// Side channel
+			chBps++
+		}
+		if cap(d.channels[ch]) < blockSize {
+			d.channels[ch] = make([]int64, blockSize)
+		}
+		d.channels[ch] = d.channels[ch][:blockSize]
+		if err := d.decodeSubframe(d.channels[ch], chBps); err != nil {
+			return 0, err
+		}
+	}
+
+	r.align()
+	frameCRC := r.crc16
+	crc, err = r.readBits(16)
+	if err != nil {
+		return 0, err
+	}
+	if uint16(crc) != frameCRC {
+		return 0, corrupt("frame CRC mismatch at byte %d", start)
+	}
+
+	left, right := d.channels[0], d.channels[1]
+	switch channelCode {
+	case 8:
+		for i := range right {
+			right[i] = left[i] - right[i]
+		}
+	case 9:
+		for i := range left {
+			left[i] += right[i]
+		}
+	case 10:
+		for i := range left {
+			mid := left[i]<<1 | right[i]&1
+			side := right[i]
+			left[i] = (mid + side) >> 1
+			right[i] = (mid - side) >> 1
+		}
+	}
+
+	d.hashSamples(blockSize, channels, bps)
+	return uint64(blockSize), nil
+}
+
+// skipCodedNumber skips the UTF-8 like coded frame or sample number.
+func (d *flacDecoder) skipCodedNumber() error {
+	first, err := d.r.readBits(8)
+	if err != nil {
+		return err
+	}
+	extra := 0
+	switch {
+	case first&0x80 == 0:
+	case first&0xe0 == 0xc0:
+		extra = 1
+	case first&0xf0 == 0xe0:
+		extra = 2
+	case first&0xf8 == 0xf0:
+		extra = 3
+	case first&0xfc == 0xf8:
+		extra = 4
+	case first&0xfe == 0xfc:
+		extra = 5
+	case first == 0xfe:
+		extra = 6
+	default:
+		return corrupt("invalid frame number")
+	}
+	for i := 0; i < extra; i++ {
+		b, err := d.r.readBits(8)
+		if err != nil {
+			return err
+		}
+		if b&0xc0 != 0x80 {
+			return corrupt("invalid frame number")
+		}
+	}
+	return nil
+}
+
+func (d *flacDecoder) decodeSubframe(samples []int64, bps uint) error {
+	r := d.r
+	header, err := r.readBits(8)
+	if err != nil {
+		return err
+	}
+	if header&0x80 != 0 {
+		return corrupt("invalid subframe header")
+	}
+	subframeType := header >> 1 & 0x3f
+
+	var wasted uint
+	if header&1 == 1 {
+		k, err := r.readUnary()
+		if err != nil {
+			return err
+		}
+		wasted = uint(k) + 1
+		if wasted >= bps {
+			return corrupt("invalid wasted bits")
+		}
+		bps -= wasted
+	}
+
+	switch {
+	case subframeType == 0:
+		v, err := r.readSigned(bps)
+		if err != nil {
+			return err
+		}
+		for i := range samples {
+			samples[i] = v
+		}
+	case subframeType == 1:
+		for i := range samples {
+			if samples[i], err = r.readSigned(bps); err != nil {
+				return err
+			}
+		}
+	case subframeType >= 8 && subframeType <= 12:
+		order := int(subframeType - 8)
+		if err := d.decodeFixed(samples, bps, order); err != nil {
+			return err
+		}
+	case subframeType >= 32:
+		order := int(subframeType&0x1f) + 1
+		if err := d.decodeLPC(samples, bps, order); err != nil {
+			return err
+		}
+	default:
+		return corrupt("reserved subframe type")
+	}
+
+	if wasted > 0 {
+		for i := range samples {
+			samples[i] <<= wasted
+		}
+	}
+	return nil
+}
+
+func (d *flacDecoder) readWarmup(samples []int64, bps uint, order int) error {
+	if order > len(samples) {
+		return corrupt("predictor order exceeds block size")
+	}
+	for i := 0; i < order; i++ {
+		var err error
+		if samples[i], err = d.r.readSigned(bps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *flacDecoder) decodeFixed(samples []int64, bps uint, order int) error {
+	if err := d.readWarmup(samples, bps, order); err != nil {
+		return err
+	}
+	if err := d.decodeResidual(samples, order); err != nil {
+		return err
+	}
+	for i := order; i < len(samples); i++ {
+		switch order {
+		case 1:
+			samples[i] += samples[i-1]
+		case 2:
+			samples[i] += 2*samples[i-1] - samples[i-2]
+		case 3:
+			samples[i] += 3*samples[i-1] - 3*samples[i-2] + samples[i-3]
+		case 4:
+			samples[i] += 4*samples[i-1] - 6*samples[i-2] + 4*samples[i-3] - samples[i-4]
+		}
+	}
+	return nil
+}
+
+func (d *flacDecoder) decodeLPC(samples []int64, bps uint, order int) error {
+	r := d.r
+	if err := d.readWarmup(samples, bps, order); err != nil {
+		return err
+	}
+	precision, err := r.readBits(4)
+	if err != nil {
+		return err
+	}
+	if precision == 15 {
+		return corrupt("invalid LPC precision")
+	}
+	shift, err := r.readSigned(5)
+	if err != nil {
+		return err
+	}
+	if shift < 0 {
+		return corrupt("negative LPC shift")
+	}
+	coeffs := make([]int64, order)
+	for i := range coeffs {
+		if coeffs[i], err = r.readSigned(uint(precision) + 1); err != nil {
+			return err
+		}
+	}
+	if err := d.decodeResidual(samples, order); err != nil {
+		return err
+	}
+	for i := order; i < len(samples); i++ {
+		var sum int64
+		for j, c := range coeffs {
+			sum += c * samples[i-1-j]
+		}
+		samples[i] += sum >> shift
+	}
+	return nil
+}
+
+// decodeResidual reads the Rice coded residual into samples[order:].
+func (d *flacDecoder) decodeResidual(samples []int64, order int) error {
+	r := d.r
+	method, err := r.readBits(2)
+	if err != nil {
+		return err
+	}
+	if method > 1 {
+		return corrupt("reserved residual coding method")
+	}
+	paramBits, escape := uint(4), uint64(15)
+	if method == 1 {
+		paramBits, escape = 5, 31
+	}
+	partitionOrder, err := r.readBits(4)
+	if err != nil {
+		return err
+	}
+	partitions := 1 << partitionOrder
+	partitionSize := len(samples) >> partitionOrder
+	if partitionSize<<partitionOrder != len(samples) || partitionSize < order {
+		return corrupt("invalid residual partition order")
+	}
+
+	i := order
+	for p := 0; p < partitions; p++ {
+		n := partitionSize
+		if p == 0 {
+			n -= order
+		}
+		param, err := r.readBits(paramBits)
+		if err != nil {
+			return err
+		}
+		if param == escape {
+			rawBits, err := r.readBits(5)
+			if err != nil {
+				return err
+			}
+			for end := i + n; i < end; i++ {
+				if samples[i], err = r.readSigned(uint(rawBits)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for end := i + n; i < end; i++ {
+			q, err := r.readUnary()
+			if err != nil {
+				return err
+			}
+			low, err := r.readBits(uint(param))
+			if err != nil {
+				return err
+			}
+			v := q<<param | low
+			samples[i] = int64(v>>1) ^ -int64(v&1)
+		}
+	}
+	return nil
+}
+
+// hashSamples adds the interleaved samples as little endian integers to the
+// MD5 hash, the same way the encoder computed the signature.
+func (d *flacDecoder) hashSamples(blockSize, channels int, bps uint) {
+	width := int(bps+7) / 8
+	size := blockSize * channels * width
+	if cap(d.buf) < size {
+		d.buf = make([]byte, size)
+	}
+	buf := d.buf[:size]
+	var tmp [8]byte
+	pos := 0
+	for i := 0; i < blockSize; i++ {
+		for ch := 0; ch < channels; ch++ {
+			binary.LittleEndian.PutUint64(tmp[:], uint64(d.channels[ch][i]))
+			pos += copy(buf[pos:pos+width], tmp[:width])
+		}
+	}
+	d.hash.Write(buf)
+}
+
+var (
+	crc8Table  [256]byte
+	crc16Table [256]uint16
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		c8 := byte(i)
+		c16 := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if c8&0x80 != 0 {
+				c8 = c8<<1 ^ 0x07
+			} else {
+				c8 <<= 1
+			}
+			if c16&0x8000 != 0 {
+				c16 = c16<<1 ^ 0x8005
+			} else {
+				c16 <<= 1
+			}
+		}
+		crc8Table[i] = c8
+		crc16Table[i] = c16
+	}
+}
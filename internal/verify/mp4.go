@@ -0,0 +1,245 @@
+package verify
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+type mp4Box struct {
+	typ     string
+	start   int64
+	payload int64
+	end     int64
+}
+
+var mp4Containers = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+	"edts": true,
+	"dinf": true,
+	"mvex": true,
+	"moof": true,
+	"traf": true,
+}
+
+type mp4Stats struct {
+	timescale    uint64
+	duration     uint64
+	hasMvhd      bool
+	fragmented   bool
+	sampleBytes  int64
+	chunkOffsets []int64
+}
+
+// verifyMP4 checks that every box fits in its parent, that the movie header
+// is present and that the sample tables point into the media data.
+func verifyMP4(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	top, err := readMP4Boxes(f, 0, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	if len(top) == 0 || top[0].typ != "ftyp" {
+		return nil, corrupt("missing ftyp box")
+	}
+
+	stats := &mp4Stats{}
+	var mdats []mp4Box
+	hasMoov := false
+	for _, b := range top {
+		switch b.typ {
+		case "moov":
+			hasMoov = true
+			if err := walkMP4(f, b, stats); err != nil {
+				return nil, err
+			}
+		case "moof":
+			stats.fragmented = true
+		case "mdat":
+			mdats = append(mdats, b)
+		}
+	}
+	if !hasMoov || !stats.hasMvhd {
+		return nil, corrupt("missing movie header")
+	}
+	if stats.timescale == 0 {
+		return nil, corrupt("invalid movie timescale")
+	}
+	if len(mdats) == 0 {
+		return nil, corrupt("missing media data")
+	}
+
+	if !stats.fragmented {
+		var mediaBytes int64
+		for _, mdat := range mdats {
+			mediaBytes += mdat.end - mdat.payload
+		}
+		if stats.sampleBytes == 0 {
+			return nil, corrupt("no samples")
+		}
+		if stats.sampleBytes > mediaBytes {
+			return nil, corrupt("samples take %d bytes, media data has %d", stats.sampleBytes, mediaBytes)
+		}
+		for _, offset := range stats.chunkOffsets {
+			inside := false
+			for _, mdat := range mdats {
+				if offset >= mdat.payload && offset < mdat.end {
+					inside = true
+					break
+				}
+			}
+			if !inside {
+				return nil, corrupt("chunk offset %d is outside of the media data", offset)
+			}
+		}
+	}
+
+	return &Info{
+		Duration: time.Duration(stats.duration) * time.Second / time.Duration(stats.timescale),
+	}, nil
+}
+
+func readMP4Boxes(f io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	var header [16]byte
+	for pos := start; pos < end; {
+		if end-pos < 8 {
+			// Padding at the end of a container
+			break
+		}
+		if _, err := f.ReadAt(header[:8], pos); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		b := mp4Box{typ: string(header[4:8]), start: pos, payload: pos + 8}
+		switch size {
+		case 0:
+			size = end - pos
+		case 1:
+			if _, err := f.ReadAt(header[8:16], pos+8); err != nil {
+				return nil, corrupt("truncated %q box at byte %d", b.typ, pos)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			b.payload += 8
+		}
+		if size < b.payload-pos || pos+size > end {
+			return nil, corrupt("%q box at byte %d exceeds its parent, the file is truncated or corrupt", b.typ, pos)
+		}
+		b.end = pos + size
+		boxes = append(boxes, b)
+		pos = b.end
+	}
+	return boxes, nil
+}
+
+func walkMP4(f io.ReaderAt, parent mp4Box, stats *mp4Stats) error {
+	children, err := readMP4Boxes(f, parent.payload, parent.end)
+	if err != nil {
+		return err
+	}
+	for _, b := range children {
+		switch {
+		case mp4Containers[b.typ]:
+			if err := walkMP4(f, b, stats); err != nil {
+				return err
+			}
+		case b.typ == "mvhd":
+			if err := readMvhd(f, b, stats); err != nil {
+				return err
+			}
+		case b.typ == "stsz", b.typ == "stco", b.typ == "co64":
+			if err := readSampleTable(f, b, stats); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readBoxPayload(f io.ReaderAt, b mp4Box) ([]byte, error) {
+	data := make([]byte, b.end-b.payload)
+	if _, err := f.ReadAt(data, b.payload); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return data, nil
+}
+
+func readMvhd(f io.ReaderAt, b mp4Box, stats *mp4Stats) error {
+	data, err := readBoxPayload(f, b)
+	if err != nil {
+		return err
+	}
+	if len(data) >= 20 && data[0] == 0 {
+		stats.timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		stats.duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	} else if len(data) >= 32 && data[0] == 1 {
+		stats.timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		stats.duration = binary.BigEndian.Uint64(data[24:32])
+	} else {
+		return corrupt("invalid movie header")
+	}
+	stats.hasMvhd = true
+	return nil
+}
+
+func readSampleTable(f io.ReaderAt, b mp4Box, stats *mp4Stats) error {
+	data, err := readBoxPayload(f, b)
+	if err != nil {
+		return err
+	}
+	if len(data) < 8 {
+		return corrupt("invalid %q box", b.typ)
+	}
+
+	switch b.typ {
+	case "stsz":
+		if len(data) < 12 {
+			return corrupt("invalid stsz box")
+		}
+		sampleSize := int64(binary.BigEndian.Uint32(data[4:8]))
+		count := int64(binary.BigEndian.Uint32(data[8:12]))
+		if sampleSize != 0 {
+			stats.sampleBytes += sampleSize * count
+			return nil
+		}
+		if int64(len(data)) < 12+count*4 {
+			return corrupt("truncated stsz box")
+		}
+		for i := int64(0); i < count; i++ {
+			stats.sampleBytes += int64(binary.BigEndian.Uint32(data[12+i*4:]))
+		}
+	case "stco", "co64":
+		count := int64(binary.BigEndian.Uint32(data[4:8]))
+		width := int64(4)
+		if b.typ == "co64" {
+			width = 8
+		}
+		if int64(len(data)) < 8+count*width {
+			return corrupt("truncated %s box", b.typ)
+		}
+		for i := int64(0); i < count; i++ {
+			entry := data[8+i*width:]
+			if width == 4 {
+				stats.chunkOffsets = append(stats.chunkOffsets, int64(binary.BigEndian.Uint32(entry)))
+			} else {
+				stats.chunkOffsets = append(stats.chunkOffsets, int64(binary.BigEndian.Uint64(entry)))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Package verify checks that downloaded audio files are complete: FLAC files
+// are fully decoded and compared against the STREAMINFO MD5 signature, and
+// the box structure of MP4 files is validated.
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCorrupt     = errors.New("corrupt file")
+	ErrUnsupported = errors.New("unsupported file format")
+)
+
+type Info struct {
+	Duration time.Duration
+}
+
+// File verifies the FLAC or MP4 file and returns its duration.
+func File(path string) (*Info, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		return verifyFLAC(path)
+	case ".m4a", ".mp4":
+		return verifyMP4(path)
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// Duration checks that the duration of the file is within the tolerance of
+// the expected duration.
+func Duration(info *Info, expected, tolerance time.Duration) error {
+	diff := info.Duration - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > tolerance {
+		return corrupt("duration %s does not match the expected %s", info.Duration.Round(time.Millisecond), expected)
+	}
+	return nil
+}
+
+func corrupt(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrCorrupt, fmt.Sprintf(format, args...))
+}
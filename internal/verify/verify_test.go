@@ -0,0 +1,353 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type bitWriter struct {
+	buf   []byte
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 0x80 >> (w.nbits % 8)
+		}
+		w.nbits++
+	}
+}
+
+func (w *bitWriter) writeSigned(v int64, n uint) {
+	w.writeBits(uint64(v)&(1<<n-1), n)
+}
+
+func (w *bitWriter) align() {
+	for w.nbits%8 != 0 {
+		w.writeBits(0, 1)
+	}
+}
+
+// writeResidual writes the residual of a subframe with the predictor order,
+// so that the first partition is shorter by the order.
+func (w *bitWriter) writeResidual(residual []int64, order int, partitionOrder uint, escapeFirst bool) {
+	w.writeBits(0, 2)
+	w.writeBits(uint64(partitionOrder), 4)
+	size := (len(residual) + order) >> partitionOrder
+	for p := 0; p < 1<<partitionOrder; p++ {
+		n := size
+		if p == 0 {
+			n -= order
+		}
+		part := residual[:n]
+		residual = residual[n:]
+		if escapeFirst && p == 0 {
+			w.writeBits(15, 4)
+			w.writeBits(20, 5)
+			for _, e := range part {
+				w.writeSigned(e, 20)
+			}
+			continue
+		}
+		const k = 6
+		w.writeBits(k, 4)
+		for _, e := range part {
+			u := uint64(e<<1) ^ uint64(e>>63)
+			for q := u >> k; q > 0; q-- {
+				w.writeBits(0, 1)
+			}
+			w.writeBits(1, 1)
+			w.writeBits(u&(1<<k-1), k)
+		}
+	}
+}
+
+const (
+	testBlockSize = 1024
+	testBps       = 16
+)
+
+type testSubframe func(w *bitWriter, x []int64, bps uint)
+
+func verbatim(w *bitWriter, x []int64, bps uint) {
+	w.writeBits(1<<1, 8)
+	for _, v := range x {
+		w.writeSigned(v, bps)
+	}
+}
+
+func constant(w *bitWriter, x []int64, bps uint) {
+	w.writeBits(0, 8)
+	w.writeSigned(x[0], bps)
+}
+
+// wastedVerbatim stores samples that are multiples of 4 with 2 wasted bits.
+func wastedVerbatim(w *bitWriter, x []int64, bps uint) {
+	w.writeBits(1<<1|1, 8)
+	w.writeBits(0b01, 2)
+	for _, v := range x {
+		w.writeSigned(v>>2, bps-2)
+	}
+}
+
+func fixed2(w *bitWriter, x []int64, bps uint) {
+	w.writeBits(10<<1, 8)
+	w.writeSigned(x[0], bps)
+	w.writeSigned(x[1], bps)
+	residual := make([]int64, len(x))
+	for i := 2; i < len(x); i++ {
+		residual[i] = x[i] - (2*x[i-1] - x[i-2])
+	}
+	w.writeResidual(residual[2:], 2, 0, false)
+}
+
+func fixed1Escaped(w *bitWriter, x []int64, bps uint) {
+	w.writeBits(9<<1, 8)
+	w.writeSigned(x[0], bps)
+	residual := make([]int64, len(x)-1)
+	for i := 1; i < len(x); i++ {
+		residual[i-1] = x[i] - x[i-1]
+	}
+	w.writeResidual(residual, 1, 1, true)
+}
+
+func lpc3(w *bitWriter, x []int64, bps uint) {
+	coeffs := []int64{20, -10, 2}
+	const precision, shift = 8, 3
+	w.writeBits((32+uint64(len(coeffs)-1))<<1, 8)
+	for i := range coeffs {
+		w.writeSigned(x[i], bps)
+	}
+	w.writeBits(precision-1, 4)
+	w.writeSigned(shift, 5)
+	for _, c := range coeffs {
+		w.writeSigned(c, precision)
+	}
+	residual := make([]int64, 0, len(x))
+	for i := len(coeffs); i < len(x); i++ {
+		var sum int64
+		for j, c := range coeffs {
+			sum += c * x[i-1-j]
+		}
+		residual = append(residual, x[i]-sum>>shift)
+	}
+	w.writeResidual(residual, len(coeffs), 2, false)
+}
+
+// encodeTestFLAC writes a stereo FLAC stream covering every subframe type
+// and channel assignment.
+func encodeTestFLAC(t *testing.T) []byte {
+	t.Helper()
+	const frames = 4
+	left := make([]int64, frames*testBlockSize)
+	right := make([]int64, frames*testBlockSize)
+	for i := range left {
+		left[i] = int64(8000 * math.Sin(float64(i)/20))
+		right[i] = int64(6000*math.Sin(float64(i)/13)) &^ 3
+	}
+	for i := 0; i < testBlockSize; i++ {
+		right[i] = -1234
+	}
+
+	hash := md5.New()
+	for i := range left {
+		binary.Write(hash, binary.LittleEndian, int16(left[i]))
+		binary.Write(hash, binary.LittleEndian, int16(right[i]))
+	}
+
+	w := &bitWriter{}
+	w.buf = append(w.buf, "fLaC"...)
+	w.nbits = 32
+	w.writeBits(1, 1)
+	w.writeBits(0, 7)
+	w.writeBits(34, 24)
+	w.writeBits(testBlockSize, 16)
+	w.writeBits(testBlockSize, 16)
+	w.writeBits(0, 24)
+	w.writeBits(0, 24)
+	w.writeBits(44100, 20)
+	w.writeBits(1, 3)
+	w.writeBits(testBps-1, 5)
+	w.writeBits(uint64(len(left)), 36)
+	w.buf = append(w.buf, hash.Sum(nil)...)
+	w.nbits += 128
+
+	frame := func(n int, channelCode uint64, ch0, ch1 []int64, sub0, sub1 testSubframe, bps0, bps1 uint) {
+		start := len(w.buf)
+		w.writeBits(0x3ffe, 14)
+		w.writeBits(0, 2)
+		w.writeBits(7, 4)
+		w.writeBits(9, 4)
+		w.writeBits(channelCode, 4)
+		w.writeBits(4, 3)
+		w.writeBits(0, 1)
+		w.writeBits(uint64(n), 8)
+		w.writeBits(testBlockSize-1, 16)
+		w.writeBits(uint64(crc8(w.buf[start:])), 8)
+		sub0(w, ch0, bps0)
+		sub1(w, ch1, bps1)
+		w.align()
+		w.writeBits(uint64(crc16(w.buf[start:])), 16)
+	}
+
+	block := func(x []int64, n int) []int64 {
+		return x[n*testBlockSize : (n+1)*testBlockSize]
+	}
+	side := func(n int) []int64 {
+		s := make([]int64, testBlockSize)
+		for i := range s {
+			s[i] = block(left, n)[i] - block(right, n)[i]
+		}
+		return s
+	}
+	mid := func(n int) []int64 {
+		m := make([]int64, testBlockSize)
+		for i := range m {
+			m[i] = (block(left, n)[i] + block(right, n)[i]) >> 1
+		}
+		return m
+	}
+
+	frame(0, 1, block(left, 0), block(right, 0), verbatim, constant, testBps, testBps)
+	frame(1, 8, block(left, 1), side(1), fixed2, fixed2, testBps, testBps+1)
+	frame(2, 10, mid(2), side(2), lpc3, lpc3, testBps, testBps+1)
+	frame(3, 1, block(left, 3), block(right, 3), fixed1Escaped, wastedVerbatim, testBps, testBps)
+	return w.buf
+}
+
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc = crc8Table[crc^b]
+	}
+	return crc
+}
+
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^b]
+	}
+	return crc
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFLAC(t *testing.T) {
+	data := encodeTestFLAC(t)
+
+	info, err := File(writeTestFile(t, "valid.flac", data))
+	if err != nil {
+		t.Fatalf("File() failed: %v", err)
+	}
+	if want := time.Duration(4*testBlockSize) * time.Second / 44100; info.Duration != want {
+		t.Errorf("Duration = %s, want %s", info.Duration, want)
+	}
+
+	if _, err := decodeFLAC(iotest.OneByteReader(bytes.NewReader(data))); err != nil {
+		t.Errorf("decodeFLAC() one byte at a time failed: %v", err)
+	}
+
+	id3v2 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), make([]byte, 5)...)
+	id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+	tagged := bytes.Join([][]byte{id3v2, data, id3v1}, nil)
+	if _, err := File(writeTestFile(t, "tagged.flac", tagged)); err != nil {
+		t.Errorf("File() with ID3 tags failed: %v", err)
+	}
+
+	flipped := bytes.Clone(data)
+	flipped[len(data)/2] ^= 0x10
+	wrongMD5 := bytes.Clone(data)
+	wrongMD5[8+18] ^= 0xff
+
+	tests := map[string][]byte{
+		"truncated":  data[:len(data)-100],
+		"flipped":    flipped,
+		"md5":        wrongMD5,
+		"no marker":  data[4:],
+		"trailing":   append(bytes.Clone(data), 1, 2, 3),
+		"no streams": data[:42],
+	}
+	for name, data := range tests {
+		if _, err := File(writeTestFile(t, "corrupt.flac", data)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: File() = %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+func box(typ string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(box, typ...), data...)
+}
+
+func TestMP4(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 5000)
+	stsz := binary.BigEndian.AppendUint32(make([]byte, 4), 0)
+	stsz = binary.BigEndian.AppendUint32(stsz, 3)
+	for i := 0; i < 3; i++ {
+		stsz = binary.BigEndian.AppendUint32(stsz, 100)
+	}
+
+	build := func(chunkOffset uint32) []byte {
+		stco := binary.BigEndian.AppendUint32(make([]byte, 4), 1)
+		stco = binary.BigEndian.AppendUint32(stco, chunkOffset)
+		return bytes.Join([][]byte{
+			box("ftyp", []byte("M4A \x00\x00\x02\x00")),
+			box("moov",
+				box("mvhd", mvhd),
+				box("trak", box("mdia", box("minf", box("stbl", box("stsz", stsz), box("stco", stco))))),
+			),
+			box("mdat", make([]byte, 300)),
+		}, nil)
+	}
+	probe := build(0)
+	data := build(uint32(len(probe) - 300))
+
+	info, err := File(writeTestFile(t, "valid.m4a", data))
+	if err != nil {
+		t.Fatalf("File() failed: %v", err)
+	}
+	if info.Duration != 5*time.Second {
+		t.Errorf("Duration = %s, want 5s", info.Duration)
+	}
+	if err := Duration(info, 5200*time.Millisecond, time.Second); err != nil {
+		t.Errorf("Duration() within tolerance failed: %v", err)
+	}
+	if err := Duration(info, 7*time.Second, time.Second); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Duration() = %v, want ErrCorrupt", err)
+	}
+
+	tests := map[string][]byte{
+		"truncated":  data[:len(data)-50],
+		"offset":     build(uint32(len(probe) + 400)),
+		"no moov":    append(box("ftyp", []byte("M4A \x00\x00\x02\x00")), box("mdat", make([]byte, 300))...),
+		"not an mp4": []byte("not an mp4 file"),
+	}
+	for name, data := range tests {
+		if _, err := File(writeTestFile(t, "corrupt.m4a", data)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: File() = %v, want ErrCorrupt", name, err)
+		}
+	}
+}